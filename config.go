@@ -0,0 +1,688 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+	"github.com/iwita/monitoring-website-stats/pkg/monitor"
+	"github.com/iwita/monitoring-website-stats/pkg/notify"
+	"github.com/iwita/monitoring-website-stats/pkg/sketch"
+	"gopkg.in/yaml.v2"
+)
+
+type Website struct {
+	// The kind of check: http (default), tcp, dns or tls
+	Type     string            `yaml:"type"`
+	Url      string            `yaml:"url"`
+	Interval float64           `yaml:"interval"`
+	Method   string            `yaml:"method"`
+	Headers  map[string]string `yaml:"headers"`
+	Body     string            `yaml:"body"`
+	// Timeout of each request in milliseconds
+	Timeout    float64     `yaml:"timeout"`
+	Assertions *Assertions `yaml:"assertions"`
+	// Days before the expiry of the certificate that the alert fires
+	CertificateExpiryDays int        `yaml:"certificateExpiryDays"`
+	Redirects             *Redirects `yaml:"redirects"`
+	// The DNS server (host:port) queried by dns checks
+	Resolver string `yaml:"resolver"`
+	Apdex    *Apdex `yaml:"apdex"`
+	SLOs     []SLO  `yaml:"slos"`
+	// The names of the notifiers of the alerts of the website, by default all of them
+	Notify []string `yaml:"notify"`
+}
+
+// SLO is a service level objective of a website, e.g. "99.9% availability over 30 days"
+type SLO struct {
+	// By default "availability", or "latency" when a latency is set
+	Name string `yaml:"name"`
+	// The percentage of the checks that must be good, e.g. 99.9
+	Objective float64 `yaml:"objective"`
+	// e.g. "30d", by default 30 days
+	Period string `yaml:"period"`
+	// In milliseconds, when set a good check must also be faster
+	Latency   float64    `yaml:"latency"`
+	BurnRates []BurnRate `yaml:"burnRates"`
+}
+
+// BurnRate is a multi-window burn-rate alerting rule of an SLO
+type BurnRate struct {
+	Long   string  `yaml:"long"`
+	Short  string  `yaml:"short"`
+	Factor float64 `yaml:"factor"`
+}
+
+// Apdex defines the user satisfaction score of a website
+type Apdex struct {
+	// The threshold T in milliseconds
+	Threshold float64 `yaml:"threshold"`
+	// The score below which the alert fires, by default it does not
+	AlertBelow float64 `yaml:"alertBelow"`
+}
+
+// Redirects define whether and how far the redirects of a website are followed
+type Redirects struct {
+	Follow   bool   `yaml:"follow"`
+	Max      int    `yaml:"max"`
+	FinalUrl string `yaml:"finalUrl"`
+}
+
+// Assertions define when a response is considered successful
+type Assertions struct {
+	// Accepted status codes, ranges ("200-399") or regular expressions ("^2..$")
+	Status          []string        `yaml:"status"`
+	BodyContains    []string        `yaml:"bodyContains"`
+	BodyNotContains []string        `yaml:"bodyNotContains"`
+	BodyMatches     []string        `yaml:"bodyMatches"`
+	JSON            []JSONAssertion `yaml:"json"`
+}
+
+type JSONAssertion struct {
+	Path   string `yaml:"path"`
+	Equals string `yaml:"equals"`
+	Exists bool   `yaml:"exists"`
+}
+
+type Configs struct {
+	Websites []Website `yaml:"websites"`
+	// The time windows of the statistics, by default 2m, 10m and 1h
+	Windows []Window `yaml:"windows"`
+	// The name of the window the availability alert is evaluated on, by default 2m
+	AlertWindow string `yaml:"alertWindow"`
+	Trend       *Trend `yaml:"trend"`
+	// The channels the alert transitions are delivered to
+	Notifiers []Notifier `yaml:"notifiers"`
+}
+
+// Notifier is a channel the alert transitions of the websites are delivered to
+type Notifier struct {
+	// By default the type, referenced by the notify list of the websites
+	Name string `yaml:"name"`
+	// stdout, file, webhook or email
+	Type string `yaml:"type"`
+	// The file the events are appended to
+	Path string `yaml:"path"`
+	// The url the events are posted to, with the headers of the request
+	Url     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// Timeout of each request in milliseconds
+	Timeout float64 `yaml:"timeout"`
+	// The SMTP server the emails are sent through, by default on port 587,
+	// and the credentials, when it requires authentication
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	// required (default), optional or disabled
+	StartTLS string `yaml:"starttls"`
+	// Whether every transition is emailed, by default true
+	Alerts *bool `yaml:"alerts"`
+	// The time of the daily digest (local time), e.g. "08:00", by default no digest is sent
+	Digest string `yaml:"digest"`
+}
+
+// The types of notifiers
+const (
+	stdoutNotifier  = "stdout"
+	fileNotifier    = "file"
+	webhookNotifier = "webhook"
+	emailNotifier   = "email"
+)
+
+// Window is a time window of statistics, e.g. "10m", "24h" or "7d"
+type Window struct {
+	// By default the duration
+	Name     string `yaml:"name"`
+	Duration string `yaml:"duration"`
+	// How often the results of the window are recalculated, by default on every display
+	Refresh string `yaml:"refresh"`
+	// The percentiles of the response times, e.g. [50, 95, 99, 99.9]
+	Percentiles []float64 `yaml:"percentiles"`
+	// Whether the window is stored in quantile sketches, for long windows
+	// or short intervals, and the relative accuracy of their percentiles
+	Sketch   bool    `yaml:"sketch"`
+	Accuracy float64 `yaml:"accuracy"`
+	// The upper bounds of the histogram buckets, e.g. ["10ms", "100ms", "1s"]
+	Buckets []string `yaml:"buckets"`
+}
+
+// Trend compares the average response time of a window against a baseline window
+type Trend struct {
+	Window   string `yaml:"window"`
+	Baseline string `yaml:"baseline"`
+	// The change of the average, in percent, below which the trend is stable. By default 10
+	Threshold *float64 `yaml:"threshold"`
+}
+
+// Returns the significance threshold of the trend as a ratio
+func (t *Trend) threshold() float64 {
+	if t.Threshold == nil {
+		return info.DefaultTrendThreshold
+	}
+	return *t.Threshold / 100
+}
+
+// The windows used, when none are configured
+var defaultWindows = []Window{
+	{Duration: "2m"},
+	{Duration: "10m"},
+	{Duration: "1h", Refresh: "3m"},
+}
+
+func readFile(cfg *Configs, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	err = decoder.Decode(&cfg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Validates the configuration and returns every problem found,
+// so that all of them can be reported at once
+func (cfg *Configs) validate() []error {
+	errs := make([]error, 0)
+	if len(cfg.Websites) == 0 {
+		errs = append(errs, fmt.Errorf("no websites defined"))
+	}
+	errs = append(errs, cfg.validateWindows()...)
+	notifiers, err := cfg.notifiers()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	seen := make(map[string]bool, 0)
+	for i, w := range cfg.Websites {
+		errs = append(errs, w.validate(i)...)
+		if seen[w.Url] {
+			errs = append(errs, fmt.Errorf("website #%d: duplicate url %q", i+1, w.Url))
+		}
+		seen[w.Url] = true
+		for _, name := range w.Notify {
+			if _, ok := notifiers[name]; !ok && err == nil {
+				errs = append(errs, fmt.Errorf("website #%d: notifier %q is not defined", i+1, name))
+			}
+		}
+	}
+	return errs
+}
+
+// Creates the configured notifiers, by name
+func (cfg *Configs) notifiers() (map[string]notify.Notifier, error) {
+	notifiers := make(map[string]notify.Notifier, len(cfg.Notifiers))
+	for i, n := range cfg.Notifiers {
+		name := n.Name
+		if name == "" {
+			name = n.Type
+		}
+		if _, ok := notifiers[name]; ok {
+			return nil, fmt.Errorf("notifier #%d: duplicate name %q", i+1, name)
+		}
+		if n.Timeout < 0 {
+			return nil, fmt.Errorf("notifier #%d: timeout must not be negative", i+1)
+		}
+		switch n.Type {
+		case stdoutNotifier:
+			notifiers[name] = notify.NewStdout()
+		case fileNotifier:
+			if n.Path == "" {
+				return nil, fmt.Errorf("notifier #%d: a file notifier requires a path", i+1)
+			}
+			notifiers[name] = notify.NewFile(n.Path)
+		case webhookNotifier:
+			if u, err := url.Parse(n.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("notifier #%d: invalid webhook url %q", i+1, n.Url)
+			}
+			notifiers[name] = notify.NewWebhook(n.Url, n.Headers, time.Duration(n.Timeout*float64(time.Millisecond)))
+		case emailNotifier:
+			email, err := n.email()
+			if err != nil {
+				return nil, fmt.Errorf("notifier #%d: %v", i+1, err)
+			}
+			notifiers[name] = email
+		default:
+			return nil, fmt.Errorf("notifier #%d: unknown type %q, must be stdout, file, webhook or email", i+1, n.Type)
+		}
+	}
+	return notifiers, nil
+}
+
+// Creates an email notifier, validating its configuration
+func (n Notifier) email() (*notify.Email, error) {
+	if n.Host == "" {
+		return nil, fmt.Errorf("an email notifier requires a host")
+	}
+	port := n.Port
+	if port == 0 {
+		port = 587
+	}
+	if port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", n.Port)
+	}
+	if _, err := mail.ParseAddress(n.From); err != nil {
+		return nil, fmt.Errorf("invalid from address %q", n.From)
+	}
+	if len(n.To) == 0 {
+		return nil, fmt.Errorf("an email notifier requires at least one recipient")
+	}
+	for _, to := range n.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("invalid recipient %q", to)
+		}
+	}
+	email := notify.NewEmail(net.JoinHostPort(n.Host, strconv.Itoa(port)), n.From, n.To)
+	email.Username, email.Password = n.Username, n.Password
+	switch n.StartTLS {
+	case "":
+	case notify.StartTLSRequired, notify.StartTLSOptional, notify.StartTLSDisabled:
+		email.StartTLS = n.StartTLS
+	default:
+		return nil, fmt.Errorf("invalid starttls %q, must be required, optional or disabled", n.StartTLS)
+	}
+	if n.Timeout > 0 {
+		email.Timeout = time.Duration(n.Timeout * float64(time.Millisecond))
+	}
+	if n.Alerts != nil {
+		email.Alerts = *n.Alerts
+	}
+	if n.Digest != "" {
+		at, err := time.Parse("15:04", n.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid digest time %q, must be e.g. 08:00", n.Digest)
+		}
+		email.Digest = true
+		email.DigestAt = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	}
+	if !email.Alerts && !email.Digest {
+		return nil, fmt.Errorf("an email notifier without alerts requires a digest")
+	}
+	return email, nil
+}
+
+// Reports whether a notifier writes to the standard output
+func (cfg *Configs) notifiesStdout() bool {
+	for _, n := range cfg.Notifiers {
+		if n.Type == stdoutNotifier {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks that the notifiers can be used along with the output mode.
+// The events of stdout notifiers would be mixed with the json or prometheus output
+func (cfg *Configs) checkOutput(output string) error {
+	if output != textOutput && cfg.notifiesStdout() {
+		return fmt.Errorf("stdout notifiers require the text output")
+	}
+	return nil
+}
+
+func (cfg *Configs) validateWindows() []error {
+	errs := make([]error, 0)
+	windows, err := cfg.windows()
+	if err != nil {
+		return append(errs, err)
+	}
+	names := make(map[string]bool, len(windows))
+	for _, w := range windows {
+		if names[w.Name] {
+			errs = append(errs, fmt.Errorf("duplicate window %q", w.Name))
+		}
+		names[w.Name] = true
+	}
+	if !names[cfg.alertWindow()] {
+		errs = append(errs, fmt.Errorf("alertWindow %q is not one of the windows", cfg.alertWindow()))
+	}
+	if t := cfg.trend(); t != nil {
+		if !names[t.Window] {
+			errs = append(errs, fmt.Errorf("trend window %q is not one of the windows", t.Window))
+		}
+		if !names[t.Baseline] {
+			errs = append(errs, fmt.Errorf("trend baseline %q is not one of the windows", t.Baseline))
+		}
+		if t.threshold() < 0 {
+			errs = append(errs, fmt.Errorf("trend threshold must not be negative"))
+		}
+	}
+	return errs
+}
+
+// Returns the configured windows, or the default ones
+func (cfg *Configs) windows() ([]monitor.Window, error) {
+	configured := cfg.Windows
+	if len(configured) == 0 {
+		configured = defaultWindows
+	}
+	windows := make([]monitor.Window, 0, len(configured))
+	for i, w := range configured {
+		d, err := parseDuration(w.Duration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("window #%d: invalid duration %q", i+1, w.Duration)
+		}
+		var refresh time.Duration
+		if w.Refresh != "" {
+			if refresh, err = parseDuration(w.Refresh); err != nil || refresh < 0 {
+				return nil, fmt.Errorf("window #%d: invalid refresh %q", i+1, w.Refresh)
+			}
+		}
+		for _, p := range w.Percentiles {
+			if err := info.ValidatePercentile(p); err != nil {
+				return nil, fmt.Errorf("window #%d: %v", i+1, err)
+			}
+		}
+		var accuracy float64
+		if w.Sketch {
+			accuracy = sketch.DefaultAccuracy
+			if w.Accuracy != 0 {
+				accuracy = w.Accuracy
+			}
+			if _, err := sketch.New(accuracy, sketch.DefaultMaxBins); err != nil {
+				return nil, fmt.Errorf("window #%d: %v", i+1, err)
+			}
+		} else if w.Accuracy != 0 {
+			return nil, fmt.Errorf("window #%d: accuracy requires sketch", i+1)
+		}
+		buckets := make([]time.Duration, len(w.Buckets))
+		for j, b := range w.Buckets {
+			if buckets[j], err = parseDuration(b); err != nil {
+				return nil, fmt.Errorf("window #%d: invalid bucket %q", i+1, b)
+			}
+		}
+		if err := info.ValidateBuckets(buckets); err != nil {
+			return nil, fmt.Errorf("window #%d: %v", i+1, err)
+		}
+		name := w.Name
+		if name == "" {
+			name = w.Duration
+		}
+		windows = append(windows, monitor.Window{
+			Name:        name,
+			Duration:    d,
+			Refresh:     refresh,
+			Percentiles: w.Percentiles,
+			Accuracy:    accuracy,
+			Buckets:     buckets,
+		})
+	}
+	return windows, nil
+}
+
+// Returns the configured alert window. By default the 2m window,
+// or the shortest window when the configured windows do not include it
+func (cfg *Configs) alertWindow() string {
+	if cfg.AlertWindow != "" {
+		return cfg.AlertWindow
+	}
+	windows, err := cfg.windows()
+	if err != nil || len(windows) == 0 {
+		return monitor.DefaultAlertWindow
+	}
+	shortest := windows[0]
+	for _, w := range windows {
+		if w.Name == monitor.DefaultAlertWindow {
+			return w.Name
+		}
+		if w.Duration < shortest.Duration {
+			shortest = w
+		}
+	}
+	return shortest.Name
+}
+
+// Returns the configured trend. By default the 10m window is compared against the 1h one,
+// or the shortest window against the longest, when the windows are configured
+func (cfg *Configs) trend() *Trend {
+	if cfg.Trend != nil {
+		return cfg.Trend
+	}
+	if len(cfg.Windows) == 0 {
+		return &Trend{Window: "10m", Baseline: "1h"}
+	}
+	windows, err := cfg.windows()
+	if err != nil || len(windows) < 2 {
+		return nil
+	}
+	shortest, longest := windows[0], windows[0]
+	for _, w := range windows[1:] {
+		if w.Duration < shortest.Duration {
+			shortest = w
+		}
+		if w.Duration > longest.Duration {
+			longest = w
+		}
+	}
+	return &Trend{Window: shortest.Name, Baseline: longest.Name}
+}
+
+// Parses a duration, additionally accepting a number of days, e.g. "7d"
+func parseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+func (w Website) validate(i int) []error {
+	errs := make([]error, 0)
+	if !w.isHTTP() {
+		if _, err := monitor.NewProber(monitor.Website{Type: w.Type, Url: w.Url}); err != nil {
+			errs = append(errs, fmt.Errorf("website #%d: %v", i+1, err))
+		}
+	} else if u, err := url.Parse(w.Url); err != nil {
+		errs = append(errs, fmt.Errorf("website #%d: invalid url %q: %v", i+1, w.Url, err))
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		errs = append(errs, fmt.Errorf("website #%d: url %q must use http or https", i+1, w.Url))
+	} else if u.Host == "" {
+		errs = append(errs, fmt.Errorf("website #%d: url %q has no host", i+1, w.Url))
+	}
+	if w.Interval < 1 {
+		errs = append(errs, fmt.Errorf("website #%d: interval must be at least 1 millisecond", i+1))
+	}
+	if w.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("website #%d: timeout must not be negative", i+1))
+	} else if w.Timeout > 0 && w.Timeout < 1 {
+		errs = append(errs, fmt.Errorf("website #%d: timeout must be at least 1 millisecond, or 0 for the default", i+1))
+	}
+	switch strings.ToUpper(w.Method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+	default:
+		errs = append(errs, fmt.Errorf("website #%d: unsupported method %q", i+1, w.Method))
+	}
+	if w.Body != "" && (strings.EqualFold(w.Method, http.MethodGet) || strings.EqualFold(w.Method, http.MethodHead) || w.Method == "") {
+		errs = append(errs, fmt.Errorf("website #%d: a body requires a method such as POST or PUT", i+1))
+	}
+	if w.CertificateExpiryDays < 0 {
+		errs = append(errs, fmt.Errorf("website #%d: certificateExpiryDays must not be negative", i+1))
+	}
+	if r := w.Redirects; r != nil {
+		if r.Max < 0 {
+			errs = append(errs, fmt.Errorf("website #%d: the maximum number of redirects must not be negative", i+1))
+		}
+		if r.FinalUrl != "" {
+			if u, err := url.Parse(r.FinalUrl); err != nil || u.Host == "" {
+				errs = append(errs, fmt.Errorf("website #%d: invalid final url %q", i+1, r.FinalUrl))
+			}
+		}
+	}
+	if a := w.Apdex; a != nil {
+		if a.Threshold <= 0 {
+			errs = append(errs, fmt.Errorf("website #%d: the apdex threshold must be a positive number of milliseconds", i+1))
+		}
+		if a.AlertBelow < 0 || a.AlertBelow > 1 {
+			errs = append(errs, fmt.Errorf("website #%d: apdex alertBelow must be between 0 and 1", i+1))
+		}
+	}
+	if _, err := w.Assertions.compile(); err != nil {
+		errs = append(errs, fmt.Errorf("website #%d: %v", i+1, err))
+	}
+	names := make(map[string]bool, len(w.SLOs))
+	for _, s := range w.SLOs {
+		slo, err := s.toMonitor()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("website #%d: %v", i+1, err))
+			continue
+		}
+		if names[slo.Name] {
+			errs = append(errs, fmt.Errorf("website #%d: duplicate slo %q", i+1, slo.Name))
+		}
+		names[slo.Name] = true
+	}
+	return errs
+}
+
+// Converts a configured objective into the type used by the monitor, validating it
+func (s SLO) toMonitor() (monitor.SLO, error) {
+	res := monitor.SLO{
+		Name: s.Name,
+		// Rounded, so that e.g. 99.9 becomes exactly 0.999
+		Objective: math.Round(s.Objective*1e7) / 1e9,
+		Period:    monitor.DefaultSLOPeriod,
+		Latency:   time.Duration(s.Latency * float64(time.Millisecond)),
+	}
+	if res.Name == "" {
+		res.Name = "availability"
+		if s.Latency > 0 {
+			res.Name = "latency"
+		}
+	}
+	if s.Objective <= 0 || s.Objective >= 100 {
+		return res, fmt.Errorf("slo %q: invalid objective %v%%, must be between 0 and 100", res.Name, s.Objective)
+	}
+	if s.Period != "" {
+		d, err := parseDuration(s.Period)
+		if err != nil {
+			return res, fmt.Errorf("slo %q: invalid period %q: %v", res.Name, s.Period, err)
+		}
+		res.Period = d
+	}
+	for _, r := range s.BurnRates {
+		long, err := parseDuration(r.Long)
+		if err != nil {
+			return res, fmt.Errorf("slo %q: invalid burn rate window %q: %v", res.Name, r.Long, err)
+		}
+		short, err := parseDuration(r.Short)
+		if err != nil {
+			return res, fmt.Errorf("slo %q: invalid burn rate window %q: %v", res.Name, r.Short, err)
+		}
+		res.BurnRates = append(res.BurnRates, monitor.BurnRate{Long: long, Short: short, Factor: r.Factor})
+	}
+	return res, res.Validate()
+}
+
+// Compiles the configured assertions into the type used by the monitor
+func (a *Assertions) compile() (*monitor.Assertions, error) {
+	if a == nil {
+		return nil, nil
+	}
+	res := &monitor.Assertions{
+		BodyContains:    a.BodyContains,
+		BodyNotContains: a.BodyNotContains,
+	}
+	for _, s := range a.Status {
+		matcher, err := monitor.ParseStatusMatcher(s)
+		if err != nil {
+			return nil, err
+		}
+		res.Status = append(res.Status, matcher)
+	}
+	for _, s := range a.BodyMatches {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body regular expression %q: %v", s, err)
+		}
+		res.BodyMatches = append(res.BodyMatches, re)
+	}
+	for _, j := range a.JSON {
+		if j.Path == "" {
+			return nil, fmt.Errorf("json assertion without a path")
+		}
+		res.JSON = append(res.JSON, monitor.JSONAssertion{
+			Path:   j.Path,
+			Equals: j.Equals,
+			Exists: j.Exists,
+		})
+	}
+	return res, nil
+}
+
+func (w Website) isHTTP() bool {
+	return w.Type == "" || w.Type == monitor.HTTPProbe
+}
+
+// Converts a configured website into the type used by the monitor
+func (w Website) toMonitor() monitor.Website {
+	timeout := monitor.DefaultTimeout
+	if w.Timeout > 0 {
+		timeout = time.Duration(w.Timeout * float64(time.Millisecond))
+	}
+	method := strings.ToUpper(w.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	// The configuration has already been validated
+	assertions, _ := w.Assertions.compile()
+	var redirects monitor.RedirectPolicy
+	if r := w.Redirects; r != nil {
+		redirects = monitor.RedirectPolicy{
+			// Requiring a final url implies following the redirects
+			Follow:   r.Follow || r.FinalUrl != "",
+			Max:      r.Max,
+			FinalUrl: r.FinalUrl,
+		}
+	}
+	var apdex monitor.Apdex
+	if a := w.Apdex; a != nil {
+		apdex = monitor.Apdex{
+			Threshold:  time.Duration(a.Threshold * float64(time.Millisecond)),
+			AlertBelow: a.AlertBelow,
+		}
+	}
+	slos := make([]monitor.SLO, 0, len(w.SLOs))
+	for _, s := range w.SLOs {
+		// The objectives have already been validated
+		slo, _ := s.toMonitor()
+		slos = append(slos, slo)
+	}
+	wb := monitor.Website{
+		Type:              w.Type,
+		Url:               w.Url,
+		Interval:          w.Interval,
+		Method:            method,
+		Headers:           w.Headers,
+		Body:              w.Body,
+		Timeout:           timeout,
+		Assertions:        assertions,
+		CertificateExpiry: time.Duration(w.CertificateExpiryDays) * 24 * time.Hour,
+		Redirects:         redirects,
+		Resolver:          w.Resolver,
+		Apdex:             apdex,
+		SLOs:              slos,
+		Notify:            w.Notify,
+		Timer:             time.NewTicker(time.Duration(w.Interval * float64(time.Millisecond))),
+	}
+	wb.Prober, _ = monitor.NewProber(wb)
+	return wb
+}
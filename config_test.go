@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/monitor"
+	"gopkg.in/yaml.v2"
+)
+
+// Test that fractional timeouts are converted without truncation, and those below 1ms are rejected
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		timeout float64
+		want    time.Duration
+		valid   bool
+	}{
+		{0, monitor.DefaultTimeout, true},
+		{1.9, 1900 * time.Microsecond, true},
+		{2500.5, 2500500 * time.Microsecond, true},
+		{0.5, 0, false},
+		{-1, 0, false},
+	}
+	for _, tt := range tests {
+		w := Website{Url: "http://example.com", Interval: 1000, Timeout: tt.timeout}
+		if errs := w.validate(0); (len(errs) == 0) != tt.valid {
+			t.Errorf("timeout %v: got errors %v, want valid %v", tt.timeout, errs, tt.valid)
+		}
+		if !tt.valid {
+			continue
+		}
+		if got := w.toMonitor().Timeout; got != tt.want {
+			t.Errorf("timeout %v: got %v, want %v", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+// Test that the alert window defaults to the shortest window, when the windows do not include 2m
+func TestAlertWindow(t *testing.T) {
+	tests := []struct {
+		windows string
+		want    string
+	}{
+		{"", monitor.DefaultAlertWindow},
+		{"windows: [{duration: 1h}, {duration: 1m}]", "1m"},
+		{"windows: [{duration: 1m}, {duration: 2m}]", "2m"},
+		{"windows: [{duration: 1h}, {duration: 1m}]\nalertWindow: 1h", "1h"},
+	}
+	for _, tt := range tests {
+		var cfg Configs
+		if err := yaml.Unmarshal([]byte("websites: [{url: \"http://example.com\", interval: 1000}]\n"+tt.windows), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		if errs := cfg.validate(); len(errs) > 0 {
+			t.Errorf("%q: got errors %v", tt.windows, errs)
+		}
+		if got := cfg.alertWindow(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.windows, got, tt.want)
+		}
+	}
+}
@@ -1,116 +1,248 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"math"
-
-	"github.com/gookit/color"
-	"github.com/iwita/monitoring-website-stats/pkg/info"
 	"github.com/iwita/monitoring-website-stats/pkg/monitor"
-	"gopkg.in/yaml.v2"
 )
 
-var start, connect time.Time
+const usage = `Usage: %s <command> [flags]
 
-type Website struct {
-	Url      string  `yaml:"url"`
-	Interval float64 `yaml:"interval"`
-}
+Commands:
+  run        monitor the websites and periodically display their statistics (default)
+  validate   check the configuration file and exit
+  once       send a single request to every website and display the results
+  report     monitor the websites for a fixed duration and display a report
 
-type Configs struct {
-	Websites []Website `yaml:"websites"`
-}
+Run '%s <command> -h' for the flags of each command.
+`
 
-func readFile(cfg *Configs, file string) error {
-	f, err := os.Open(file)
-	if err != nil {
-		return err
+func main() {
+	args := os.Args[1:]
+	command := "run"
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		command, args = args[0], args[1:]
 	}
-	defer f.Close()
 
-	decoder := yaml.NewDecoder(f)
-	err = decoder.Decode(&cfg)
+	var err error
+	switch command {
+	case "run":
+		err = runCmd(args)
+	case "validate":
+		err = validateCmd(args)
+	case "once":
+		err = onceCmd(args)
+	case "report":
+		err = reportCmd(args)
+	case "help":
+		fmt.Printf(usage, os.Args[0], os.Args[0])
+	default:
+		fmt.Fprintf(os.Stderr, usage, os.Args[0], os.Args[0])
+		err = fmt.Errorf("unknown command %q", command)
+	}
 	if err != nil {
-		return err
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	return nil
 }
 
-func main() {
-	var cfg Configs
-	err := readFile(&cfg, "files/input.yaml")
+// Continuously monitors the websites and prints their statistics
+func runCmd(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	configFile := fs.String("config", "files/input.yaml", "path of the configuration file")
+	display := fs.Duration("display", 3*time.Second, "how often the statistics are displayed")
+	output := fs.String("output", textOutput, "output mode (text, json or prometheus)")
+	watch := fs.Duration("watch", 5*time.Second, "how often the configuration file is checked for changes (0 disables it)")
+	summary := fs.String("summary", "", "file the final summary is written to on exit (by default the standard output)")
+	fs.Parse(args)
+
+	dd, cfg, err := setup(*configFile, *output)
 	if err != nil {
-		fmt.Println(err)
+		return err
 	}
-	dd := monitor.NewMonitor()
-	for _, w := range cfg.Websites {
+	if *display <= 0 {
+		return fmt.Errorf("display interval must be positive")
+	}
+	p := newPrinter(os.Stdout, *output, cfg)
 
-		// [1] TODO:
-		// include website unable to reach at the moment
-		_, err := http.Get(w.Url)
-		if err != nil {
-			_, netErr := http.Get("https://www.google.com")
-			if netErr != nil {
-				fmt.Println("No internet connection")
-				os.Exit(1)
-			} else {
-				//fmt.Println("Unable to reach", w.Url)
-				// fmt.Println(err)
-				// [1] continue
-			}
-		}
+	// The results of each window are refreshed on the display ticks they are due
+	timer := time.NewTicker(*display)
 
-		dd.Wbs = append(dd.Wbs, monitor.Website{
-			Url:      w.Url,
-			Interval: w.Interval,
-			Timer:    time.NewTicker(time.Millisecond * time.Duration(w.Interval)),
-			Res1h: &info.Result{
-				Max:          -1,
-				Average:      -1,
-				Percentile:   -1,
-				StatusCodes:  "",
-				Availability: -1,
-			},
-		})
-	}
-	// Initialize the two output counters
-	timer1 := time.NewTicker(time.Second * time.Duration(3))
-	timer2 := time.NewTicker(time.Minute * time.Duration(3))
+	// Monitoring stops on SIGINT or SIGTERM
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	// The configuration is reloaded on SIGHUP, or when the file changes
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	var watchTicks <-chan time.Time
+	w := newWatcher(*configFile)
+	if *watch > 0 {
+		watchTicker := time.NewTicker(*watch)
+		defer watchTicker.Stop()
+		watchTicks = watchTicker.C
+	}
 
 	// Start the monitoring
 	go dd.Exec()
-	var trend string
 	for {
 		select {
-		case <-timer1.C:
-			for _, wb := range dd.Wbs {
-				websiteName := color.FgBlue.Render(wb.Url)
-				alertOut := dd.StatsPerWebsite[wb.Url].TwoMinutesInfo.Alert.PrintTest()
-				wb.Res10m = dd.StatsPerWebsite[wb.Url].TenMinutesInfo.GetResult()
-				final := time.Duration(int(dd.StatsPerWebsite[wb.Url].TenMinutesInfo.SumResponses) / dd.StatsPerWebsite[wb.Url].TenMinutesInfo.TotalResponses)
-				start := time.Duration(int(dd.StatsPerWebsite[wb.Url].OneHourInfo.SumResponses) / dd.StatsPerWebsite[wb.Url].OneHourInfo.TotalResponses)
-				percentage := float64(final-start) / float64(start)
-				if percentage < 0 {
-					trend = fmt.Sprintf("%.2v%% faster than past hour", math.Abs(percentage)*100)
-				} else if percentage == 0 {
-					trend = fmt.Sprint("Stable trend")
-				} else {
-					trend = fmt.Sprintf("%.2v%% slower than past hour", percentage*100)
-				}
-				// fmt.Println(monitor.Header)
-				fmt.Printf(monitor.OutputTemplate, websiteName, alertOut,
-					wb.Res10m.Max, wb.Res10m.Average, wb.Res10m.Percentile, trend, wb.Res10m.Availability, wb.Res10m.StatusCodes,
-					wb.Res1h.Max, wb.Res1h.Average, wb.Res1h.Percentile, wb.Res1h.Availability, wb.Res1h.StatusCodes)
-			}
+		case <-timer.C:
+			p.print(dd.Snapshot())
+
+		case <-hup:
+			w.changed()
+			reload(dd, cfg, *configFile, *output)
 
-		case <-timer2.C:
-			for i, wb := range dd.Wbs {
-				dd.Wbs[i].Res1h = dd.StatsPerWebsite[wb.Url].OneHourInfo.GetResult()
+		case <-watchTicks:
+			if w.changed() {
+				reload(dd, cfg, *configFile, *output)
 			}
+
+		case sig := <-stop:
+			timer.Stop()
+			fmt.Fprintf(os.Stderr, "received %v, stopping\n", sig)
+			dd.Stop()
+			return writeSummary(dd, cfg, *output, *summary)
 		}
 	}
 }
+
+// Reads and validates the configuration file without monitoring anything
+func validateCmd(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	configFile := fs.String("config", "files/input.yaml", "path of the configuration file")
+	fs.Parse(args)
+
+	if _, err := loadConfig(*configFile); err != nil {
+		return err
+	}
+	fmt.Printf("%s: OK\n", *configFile)
+	return nil
+}
+
+// Sends a single request to every website and prints the outcome
+func onceCmd(args []string) error {
+	fs := flag.NewFlagSet("once", flag.ExitOnError)
+	configFile := fs.String("config", "files/input.yaml", "path of the configuration file")
+	output := fs.String("output", textOutput, "output mode (text, json or prometheus)")
+	fs.Parse(args)
+
+	dd, cfg, err := setup(*configFile, *output)
+	if err != nil {
+		return err
+	}
+	// Abort the requests in flight on SIGINT or SIGTERM
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		dd.Stop()
+	}()
+	dd.Once()
+	dd.Stop()
+	return writeSummary(dd, cfg, *output, "")
+}
+
+// Monitors the websites for the given duration and prints a single report
+func reportCmd(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	configFile := fs.String("config", "files/input.yaml", "path of the configuration file")
+	duration := fs.Duration("duration", time.Minute, "how long to monitor the websites before reporting")
+	output := fs.String("output", textOutput, "output mode (text, json or prometheus)")
+	summary := fs.String("summary", "", "file the report is written to (by default the standard output)")
+	fs.Parse(args)
+
+	if *duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	dd, cfg, err := setup(*configFile, *output)
+	if err != nil {
+		return err
+	}
+	// Report early on SIGINT or SIGTERM
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go dd.Exec()
+	select {
+	case <-time.After(*duration):
+	case sig := <-stop:
+		fmt.Fprintf(os.Stderr, "received %v, stopping\n", sig)
+	}
+	dd.Stop()
+	return writeSummary(dd, cfg, *output, *summary)
+}
+
+// Writes the results of every window and the alert history of each website,
+// once monitoring has stopped
+func writeSummary(dd *monitor.Monitor, cfg *Configs, output, file string) error {
+	if file == "" {
+		return newPrinter(os.Stdout, output, cfg).print(dd.SnapshotNow())
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := newPrinter(f, output, cfg).print(dd.SnapshotNow()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Loads the configuration file and reports every validation error
+func loadConfig(file string) (*Configs, error) {
+	var cfg Configs
+	if err := readFile(&cfg, file); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", file, err)
+	}
+	if errs := cfg.validate(); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+		}
+		return nil, fmt.Errorf("%s: %d error(s) found", file, len(errs))
+	}
+	return &cfg, nil
+}
+
+// Loads the configuration and creates a Monitor including all the configured websites
+func setup(file, output string) (*monitor.Monitor, *Configs, error) {
+	if output != textOutput && output != jsonOutput && output != prometheusOutput {
+		return nil, nil, fmt.Errorf("unknown output mode %q", output)
+	}
+	cfg, err := loadConfig(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.checkOutput(output); err != nil {
+		return nil, nil, err
+	}
+	dd := monitor.NewMonitor()
+	windows, _ := cfg.windows()
+	if err := dd.SetWindows(windows, cfg.alertWindow()); err != nil {
+		return nil, nil, err
+	}
+	if t := cfg.trend(); t != nil {
+		if err := dd.SetTrend(t.Window, t.Baseline, t.threshold()); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Websites that cannot be reached are monitored as well, and reported as down
+	for _, w := range cfg.Websites {
+		dd.Wbs = append(dd.Wbs, w.toMonitor())
+	}
+	notifiers, _ := cfg.notifiers()
+	if err := dd.SetNotifiers(notifiers); err != nil {
+		return nil, nil, err
+	}
+	return dd, cfg, nil
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+	"github.com/iwita/monitoring-website-stats/pkg/monitor"
+)
+
+// The supported output modes
+const (
+	textOutput       = "text"
+	jsonOutput       = "json"
+	prometheusOutput = "prometheus"
+)
+
+// Type used to encode the statistics of a single website in json
+type websiteReport struct {
+	Url              string               `json:"url"`
+	State            string               `json:"state"`
+	Availability     float64              `json:"availability"`
+	Trend            *info.Trend          `json:"trend,omitempty"`
+	Windows          []windowReport       `json:"windows"`
+	Since            *time.Time           `json:"since,omitempty"`
+	Overall          *info.Result         `json:"overall,omitempty"`
+	Certificate      *info.Certificate    `json:"certificate,omitempty"`
+	CertificateState string               `json:"certificateState,omitempty"`
+	Redirects        []info.Redirect      `json:"redirects,omitempty"`
+	SLOs             []*monitor.SLOResult `json:"slos,omitempty"`
+	Incidents        []alert.Incident     `json:"incidents,omitempty"`
+	// The statistics of the incidents since monitoring started
+	IncidentStats *alert.IncidentStats `json:"incidentStats,omitempty"`
+	// The history of the last days
+	Days []dayReport `json:"days,omitempty"`
+}
+
+// Type used to encode a day of the history of a website in json
+type dayReport struct {
+	Start        time.Time     `json:"start"`
+	Checks       int           `json:"checks"`
+	Availability float64       `json:"availability"`
+	Average      time.Duration `json:"average"`
+	P95          time.Duration `json:"p95"`
+	Max          time.Duration `json:"max"`
+}
+
+type windowReport struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Result   *info.Result  `json:"result"`
+}
+
+type report struct {
+	Time     time.Time       `json:"time"`
+	Websites []websiteReport `json:"websites"`
+}
+
+// printer writes the statistics of the monitored websites
+// in the selected output mode.
+// The output of every display tick is buffered and flushed at once
+type printer struct {
+	w     *bufio.Writer
+	mode  string
+	trend *Trend
+}
+
+func newPrinter(w io.Writer, mode string, cfg *Configs) *printer {
+	return &printer{
+		w:     bufio.NewWriter(w),
+		mode:  mode,
+		trend: cfg.trend(),
+	}
+}
+
+// Prints a snapshot of the statistics of each website
+func (p *printer) print(snap *monitor.Snapshot) error {
+	switch p.mode {
+	case jsonOutput:
+		p.printJSON(snap)
+	case prometheusOutput:
+		p.printPrometheus(snap)
+	default:
+		for _, ws := range snap.Websites {
+			p.printText(snap.Windows, ws)
+		}
+	}
+	return p.w.Flush()
+}
+
+func (p *printer) printText(windows []monitor.Window, ws monitor.WebsiteSnapshot) {
+	websiteName := color.FgBlue.Render(ws.Url)
+	if !ws.Ready {
+		fmt.Fprintf(p.w, "%s\nMetrics currently unavailable\n", websiteName)
+		return
+	}
+	alertOut := ws.Alert.PrintTest()
+	if ws.Certificate != nil {
+		alertOut += certificateLine(ws.Certificate) + ws.CertificateAlert.PrintTest()
+	}
+	if len(ws.Redirects) > 0 {
+		alertOut += redirectLine(ws.Redirects)
+	}
+	for _, slo := range ws.SLOs {
+		alertOut += sloLine(slo.Result) + slo.Alert.PrintTest()
+	}
+	fmt.Fprintf(p.w, monitor.HeaderTemplate, websiteName, alertOut)
+	for j, w := range windows {
+		res := ws.Results[j]
+		if res == nil {
+			fmt.Fprintf(p.w, monitor.EmptyWindowTemplate, w.Name)
+			continue
+		}
+		trendOut := ""
+		if ws.Trend != nil && p.trend.Window == w.Name {
+			trendOut = fmt.Sprintf(" (%s)", p.trendLine(*ws.Trend))
+		}
+		apdexOut := ""
+		if res.Apdex != nil {
+			apdexOut = "\t" + res.Apdex.String()
+		}
+		fmt.Fprintf(p.w, monitor.WindowTemplate, w.Name, res.Max, res.Average, percentileLine(res.Percentiles),
+			trendOut, res.Availability, apdexOut, statusLines(res), distributionLine(res)+timingLines(res))
+	}
+	if res := ws.Overall; res != nil {
+		fmt.Fprintf(p.w, monitor.OverallTemplate, res.Max, res.Average, percentileLine(res.Percentiles), res.Availability,
+			ws.Since.Format("2006-01-02 15:04:05"), res.Responses, statusLines(res))
+	}
+	fmt.Fprint(p.w, monitor.FooterTemplate)
+}
+
+// Formats the details of a certificate
+func certificateLine(c *info.Certificate) string {
+	return fmt.Sprintf("certificate: %s, issued by %s, expires %v (in %v days), names %v\n",
+		c.Subject, c.Issuer, c.NotAfter.Format("2006-01-02 15:04:05"), int(c.ExpiresIn().Hours()/24), strings.Join(c.DNSNames, ", "))
+}
+
+// Formats the error budget and the burn rates of an objective, e.g.
+// "slo availability: 99.9% over 30d, sli 99.95%, 50.0% of the error budget left, burn rate 1h 0.0x / 5m 0.0x (alert at 14.4x)"
+func sloLine(r *monitor.SLOResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "slo %s: %v%%", r.Name, r.Objective*100)
+	if r.Latency > 0 {
+		fmt.Fprintf(&b, " under %v", r.Latency)
+	}
+	fmt.Fprintf(&b, " over %s, sli %.3f%%, %.1f%% of the error budget left", shortDuration(r.Period), r.SLI*100, r.ErrorBudget*100)
+	for _, br := range r.BurnRates {
+		fmt.Fprintf(&b, ", burn rate %s %.1fx / %s %.1fx (alert at %vx)", shortDuration(br.Long), br.LongRate, shortDuration(br.Short), br.ShortRate, br.Factor)
+	}
+	return b.String() + "\n"
+}
+
+// Formats a duration without its zero units, e.g. "30d", "1h" or "5m30s"
+func shortDuration(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// Formats the chain of redirects followed by the most recent request
+func redirectLine(redirects []info.Redirect) string {
+	hops := make([]string, len(redirects))
+	for i, r := range redirects {
+		hops[i] = fmt.Sprintf("%s (%d, %v)", r.Url, r.Status, r.Duration.Round(time.Millisecond))
+	}
+	return "redirects: " + strings.Join(hops, " -> ") + "\n"
+}
+
+// Formats the percentiles of a result, e.g. "p50 2ms p99 10ms"
+func percentileLine(percentiles []info.PercentileResult) string {
+	values := make([]string, len(percentiles))
+	for i, p := range percentiles {
+		values[i] = fmt.Sprintf("%s %v", p.Name(), p.Value)
+	}
+	return strings.Join(values, " ")
+}
+
+// The levels of the bars of the histogram, from an empty bucket to the fullest one
+var bars = []rune(" ▁▂▃▄▅▆▇█")
+
+// Formats the distribution of the response times of a result,
+// with an inline bar per histogram bucket
+func distributionLine(res *info.Result) string {
+	h := res.Histogram
+	if h == nil {
+		return ""
+	}
+	counts := h.Buckets()
+	max := 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	bar := make([]rune, len(counts))
+	for j, c := range counts {
+		level := 0
+		if max > 0 {
+			level = (c*(len(bars)-1) + max - 1) / max
+		}
+		bar[j] = bars[level]
+	}
+	var from, to string
+	if len(h.Bounds) > 0 {
+		from, to = fmt.Sprintf("<=%v ", h.Bounds[0]), fmt.Sprintf(" >%v", h.Bounds[len(h.Bounds)-1])
+	}
+	return fmt.Sprintf("distribution %s[%s]%s  min %v  stddev %v  jitter %v\n",
+		from, string(bar), to, res.Min, res.StdDev, res.Jitter)
+}
+
+// Formats the phases of the requests of a result
+func timingLines(res *info.Result) string {
+	t := res.Timings
+	if t == nil {
+		return ""
+	}
+	phase := func(p info.PhaseResult) string {
+		return fmt.Sprintf("[%v/%v/%v]", p.Max, p.Average, p.Percentile)
+	}
+	return fmt.Sprintf("timings [Max / Avg / 90th percentile]\n"+
+		"  dns %s  connect %s  tls %s\n"+
+		"  server %s  transfer %s  total %s\n",
+		phase(t.DNS), phase(t.Connect), phase(t.TLS),
+		phase(t.Server), phase(t.Transfer), phase(t.Total))
+}
+
+// Formats the status codes and the failure reasons of a result
+func statusLines(res *info.Result) string {
+	var b strings.Builder
+	if sc := res.StatusCodes; sc != nil {
+		for _, c := range sc.Codes {
+			fmt.Fprintf(&b, "status %v => %v\n", c.Code, c.Count)
+		}
+		classes := make([]string, len(sc.Classes))
+		for j, c := range sc.Classes {
+			classes[j] = fmt.Sprintf("%v %v", c.Class, c.Count)
+		}
+		fmt.Fprintf(&b, "classes [%s]  error rate %.2f%%\n", strings.Join(classes, ", "), sc.ErrorRate*100)
+	}
+	if res.Coverage < 100 {
+		fmt.Fprintf(&b, "no responses for %.2f%% of the window\n", 100-res.Coverage)
+	}
+	reasons := make([]string, 0, len(res.Failures))
+	for reason := range res.Failures {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Fprintf(&b, "failed %v => %v\n", reason, res.Failures[reason])
+	}
+	classes := make([]string, 0, len(res.FailureClasses))
+	for class := range res.FailureClasses {
+		classes = append(classes, string(class))
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		fmt.Fprintf(&b, "error %v => %v\n", class, res.FailureClasses[info.FailureClass(class)])
+	}
+	return b.String()
+}
+
+func (p *printer) printJSON(snap *monitor.Snapshot) {
+	r := report{
+		Time:     snap.Time,
+		Websites: make([]websiteReport, 0, len(snap.Websites)),
+	}
+	for _, ws := range snap.Websites {
+		wr := websiteReport{
+			Url:     ws.Url,
+			Windows: make([]windowReport, 0, len(snap.Windows)),
+		}
+		for j, w := range snap.Windows {
+			wr.Windows = append(wr.Windows, windowReport{
+				Name:     w.Name,
+				Duration: w.Duration,
+			})
+			if j < len(ws.Results) {
+				wr.Windows[j].Result = ws.Results[j]
+			}
+		}
+		if ws.Ready {
+			wr.Trend = ws.Trend
+			if !ws.Since.IsZero() {
+				since := ws.Since
+				wr.Since = &since
+				wr.Overall = ws.Overall
+			}
+			wr.State = ws.Alert.AlertState.String()
+			wr.Availability = ws.Alert.Availability * 100
+			if len(ws.Alert.Incidents) > 0 {
+				st := ws.Alert.Stats(ws.Alert.Since, snap.Time)
+				wr.Incidents = ws.Alert.Incidents
+				wr.IncidentStats = &st
+			}
+			wr.Redirects = ws.Redirects
+			for _, slo := range ws.SLOs {
+				wr.SLOs = append(wr.SLOs, slo.Result)
+			}
+			if ws.Certificate != nil {
+				wr.Certificate = ws.Certificate
+				wr.CertificateState = ws.CertificateAlert.State.String()
+			}
+			for _, d := range ws.Days {
+				wr.Days = append(wr.Days, dayReport{
+					Start:        d.Start,
+					Checks:       d.Count,
+					Availability: d.Availability() * 100,
+					Average:      d.Average().Round(time.Millisecond),
+					P95:          d.Percentile(95).Round(time.Millisecond),
+					Max:          d.Max.Round(time.Millisecond),
+				})
+			}
+		}
+		r.Websites = append(r.Websites, wr)
+	}
+	enc := json.NewEncoder(p.w)
+	if err := enc.Encode(r); err != nil {
+		fmt.Fprintln(p.w, err)
+	}
+}
+
+// Formats a trend, e.g. "12.5% faster than past 1h, -1ms/min"
+func (p *printer) trendLine(t info.Trend) string {
+	var res string
+	switch t.Direction {
+	case info.TrendUnknown:
+		return "Trend currently unavailable"
+	case info.TrendStable:
+		res = "Stable trend"
+	default:
+		res = fmt.Sprintf("%.1f%% %s than past %s", math.Abs(t.Change)*100, t.Direction, p.trend.Baseline)
+	}
+	slope := t.Slope.Round(time.Microsecond)
+	if slope > 0 {
+		return fmt.Sprintf("%s, +%v/min", res, slope)
+	} else if slope < 0 {
+		return fmt.Sprintf("%s, %v/min", res, slope)
+	}
+	return res
+}
@@ -21,6 +21,16 @@ const (
 	Unavailable
 )
 
+func (s State) String() string {
+	switch s {
+	case Available:
+		return "UP"
+	case Unavailable:
+		return "DOWN"
+	}
+	return "UNKNOWN"
+}
+
 type Alert struct {
 
 	// The state of the alert (according to the FSM logic)
@@ -32,67 +42,193 @@ type Alert struct {
 	// Stores the availability of the website
 	Availability float64
 
-	// Slice of time.Time that the website went from Down to Up
-	// It is initialized with the time the system started (went up for the first time)
-	LastTimeAvailable []time.Time
+	// The Apdex score below which the alert fires, 0 when it is not an alert condition
+	ApdexThreshold float64
+
+	// Stores the Apdex score of the website, when ApdexThreshold is set
+	Apdex float64
+
+	// The reason of the last failed response, when the website went down
+	Reason string
+
+	// The time the alert was created, i.e. when monitoring started
+	Since time.Time
 
-	// Slice of time.Time that the website went from Up to Down
-	LastTimeUnavailable []time.Time
+	// Every time the website went down, in the order they started.
+	// Only the last one may be ongoing
+	Incidents []Incident
+}
+
+// Incident is a period during which the website was down
+type Incident struct {
+	Start time.Time `json:"start"`
+	// Zero while the incident is ongoing
+	End time.Time `json:"end"`
+	// Why the website went down
+	Reason string `json:"reason"`
+	// The lowest availability seen during the incident
+	MinAvailability float64 `json:"minAvailability"`
+}
+
+// Reports whether the website is still down
+func (in Incident) Ongoing() bool {
+	return in.End.IsZero()
+}
+
+// Returns the duration of the incident, up to now when it is ongoing
+func (in Incident) Duration(now time.Time) time.Duration {
+	if in.Ongoing() {
+		return now.Sub(in.Start)
+	}
+	return in.End.Sub(in.Start)
+}
+
+// IncidentStats summarizes the incidents of a period
+type IncidentStats struct {
+	// The number of incidents during the period, including the ongoing ones
+	Incidents int `json:"incidents"`
+	// The time the website was down within the period
+	Downtime time.Duration `json:"downtime"`
+	// Mean time to recovery, the average duration of the incidents that were resolved within the period
+	MTTR time.Duration `json:"mttr"`
+	// Mean time between failures, the time the website was up within the period per incident
+	MTBF time.Duration `json:"mtbf"`
 }
 
 func NewAlert(t float64) *Alert {
-	al := &Alert{
-		AlertState:          Available,
-		Threshold:           t,
-		LastTimeAvailable:   make([]time.Time, 0),
-		LastTimeUnavailable: make([]time.Time, 0),
+	return &Alert{
+		AlertState: Available,
+		Threshold:  t,
+		Since:      time.Now(),
+		Incidents:  make([]Incident, 0),
+	}
+}
+
+// Returns a copy of the alert that is not affected by later updates
+func (a *Alert) Clone() *Alert {
+	c := *a
+	c.Incidents = append(make([]Incident, 0, len(a.Incidents)), a.Incidents...)
+	return &c
+}
+
+// Updates the state of the alert at the given time, given whether the website is healthy,
+// and why it is not. It opens an incident when the website goes down and closes it when
+// it recovers, keeping the lowest availability seen in between.
+// It returns true when the state changed
+func (a *Alert) Update(t time.Time, healthy bool, reason string) bool {
+	switch a.AlertState {
+	case Available:
+		if healthy {
+			return false
+		}
+		a.Reason = reason
+		a.AlertState = Unavailable
+		a.Incidents = append(a.Incidents, Incident{
+			Start:           t,
+			Reason:          reason,
+			MinAvailability: a.Availability,
+		})
+		return true
+	case Unavailable:
+		in := &a.Incidents[len(a.Incidents)-1]
+		if a.Availability < in.MinAvailability {
+			in.MinAvailability = a.Availability
+		}
+		if !healthy {
+			return false
+		}
+		in.End = t
+		a.AlertState = Available
+		return true
 	}
-	al.LastTimeAvailable = append(al.LastTimeAvailable, time.Now())
-	return al
+	return false
 }
 
-// Function in order to test the functionality of the alert
+// Returns the time of the latest transition, or the time the alert was created
+func (a *Alert) StateSince() time.Time {
+	n := len(a.Incidents)
+	if n == 0 {
+		return a.Since
+	}
+	if in := a.Incidents[n-1]; in.Ongoing() {
+		return in.Start
+	}
+	return a.Incidents[n-1].End
+}
+
+// Summarizes the incidents that overlap the period from..to, counting only their part within it
+func (a *Alert) Stats(from, to time.Time) IncidentStats {
+	var res IncidentStats
+	var resolved int
+	var repair time.Duration
+	for _, in := range a.Incidents {
+		end := in.End
+		if in.Ongoing() || end.After(to) {
+			end = to
+		}
+		start := in.Start
+		if start.Before(from) {
+			start = from
+		}
+		if !end.After(start) {
+			continue
+		}
+		res.Incidents++
+		res.Downtime += end.Sub(start)
+		if !in.Ongoing() && !in.End.After(to) {
+			resolved++
+			repair += in.Duration(to)
+		}
+	}
+	if resolved > 0 {
+		res.MTTR = repair / time.Duration(resolved)
+	}
+	if res.Incidents > 0 {
+		res.MTBF = (to.Sub(from) - res.Downtime) / time.Duration(res.Incidents)
+	}
+	return res
+}
+
+// Returns the current state of the alert, its incidents and their statistics since the alert was created
 func (a *Alert) PrintTest() string {
 	red := color.FgRed.Render
 	green := color.FgGreen.Render
 	var res strings.Builder
-	if len(a.LastTimeAvailable) == len(a.LastTimeUnavailable) {
-		res.WriteString(fmt.Sprintf(red("STATUS: DOWN, Availability: %0.2f%%, Since: %v, Duration: %v\n"), a.Availability*100, a.LastTimeUnavailable[len(a.LastTimeUnavailable)-1].Format("2006-01-02 15:04:05"),
-			time.Since(a.LastTimeUnavailable[len(a.LastTimeUnavailable)-1]).Round((time.Millisecond))))
-	} else if len(a.LastTimeAvailable) > len(a.LastTimeUnavailable) {
-		res.WriteString(fmt.Sprintf(green("STATUS: UP, Availability: %0.2f%%, Since: %v, Duration: %v\n"), a.Availability*100, a.LastTimeAvailable[len(a.LastTimeAvailable)-1].Format("2006-01-02 15:04:05"),
-			time.Since(a.LastTimeAvailable[len(a.LastTimeAvailable)-1]).Round(time.Millisecond)))
-	} else {
-		fmt.Println("Inside the else statement in alert.go")
+	now := time.Now()
+	since := a.StateSince()
+	switch a.AlertState {
+	case Unavailable:
+		res.WriteString(fmt.Sprintf(red("STATUS: DOWN, Availability: %0.2f%%, Since: %v, Duration: %v\n"), a.Availability*100,
+			since.Format("2006-01-02 15:04:05"), now.Sub(since).Round(time.Millisecond)))
+		if a.Reason != "" {
+			res.WriteString(fmt.Sprintf(red("Reason: %s\n"), a.Reason))
+		}
+	default:
+		res.WriteString(fmt.Sprintf(green("STATUS: UP, Availability: %0.2f%%, Since: %v, Duration: %v\n"), a.Availability*100,
+			since.Format("2006-01-02 15:04:05"), now.Sub(since).Round(time.Millisecond)))
 	}
-	res.WriteString(fmt.Sprintf("Unavailable		|	Available again\n"))
-	for i := 0; i < len(a.LastTimeUnavailable); i++ {
-		res.WriteString(fmt.Sprint(a.LastTimeUnavailable[i].Format("2006-01-02 15:04:05"), "		"))
-		if i+1 < len(a.LastTimeAvailable) {
-			res.WriteString(fmt.Sprintf("%v\n", a.LastTimeAvailable[i].Format("2006-01-02 15:04:05")))
+	if a.ApdexThreshold > 0 {
+		res.WriteString(fmt.Sprintf("Apdex: %0.2f (alert below %0.2f)\n", a.Apdex, a.ApdexThreshold))
+	}
+	if len(a.Incidents) == 0 {
+		return res.String()
+	}
+	res.WriteString("Unavailable		|	Available again		|	Duration	|	Min availability	|	Reason\n")
+	for _, in := range a.Incidents {
+		end := "ongoing		"
+		if !in.Ongoing() {
+			end = in.End.Format("2006-01-02 15:04:05")
 		}
+		res.WriteString(fmt.Sprintf("%v		%v		%v		%0.2f%%			%s\n", in.Start.Format("2006-01-02 15:04:05"), end,
+			in.Duration(now).Round(time.Millisecond), in.MinAvailability*100, in.Reason))
 	}
+	st := a.Stats(a.Since, now)
+	res.WriteString(fmt.Sprintf("Incidents: %d, Downtime: %v, MTTR: %v, MTBF: %v\n", st.Incidents,
+		st.Downtime.Round(time.Millisecond), st.MTTR.Round(time.Millisecond), st.MTBF.Round(time.Millisecond)))
 	return res.String()
 }
 
 // Function that prints the alert
 func (a *Alert) Print() {
-	red := color.FgRed.Render
-	green := color.FgGreen.Render
-	if len(a.LastTimeAvailable) == len(a.LastTimeUnavailable) {
-		fmt.Printf(red("STATUS: DOWN, Availability: %0.2f, Since: %v, Duration: %v\n"), a.Availability*100, a.LastTimeUnavailable[len(a.LastTimeUnavailable)-1].Format("2006-01-02 15:04:05"),
-			time.Since(a.LastTimeUnavailable[len(a.LastTimeUnavailable)-1]))
-	} else if len(a.LastTimeAvailable) > len(a.LastTimeUnavailable) {
-
-		fmt.Printf(green("STATUS: UP, Availability: %v%%, Since: %v, Duration: %v\n"), a.Availability*100, a.LastTimeAvailable[len(a.LastTimeAvailable)-1].Format("2006-01-02 15:04:05"),
-			time.Since(a.LastTimeAvailable[len(a.LastTimeAvailable)-1]))
-	}
-	fmt.Printf("Lower Threshold Violation	|	Upper Thereshold Violation\n")
-	for i := 0; i < len(a.LastTimeUnavailable); i++ {
-		fmt.Print(a.LastTimeUnavailable[i])
-		if i+1 < len(a.LastTimeAvailable) {
-			fmt.Print(a.LastTimeUnavailable[i].Format("2006-01-02 15:04:05"), "   ")
-		}
-		fmt.Println()
-	}
+	fmt.Print(a.PrintTest())
 }
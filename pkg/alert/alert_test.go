@@ -15,12 +15,11 @@ func TestUP(t *testing.T) {
 
 	want := strings.Builder{}
 	a := NewAlert(0.8)
-	start := time.Now()
-	a.Availability = 90
+	start := a.Since
+	a.Availability = 0.9
 	res := a.PrintTest()
-	want.WriteString(fmt.Sprintf(green("STATUS: UP, Availability: 90, Since: %v, Duration: %v\n"), start.Format("2006-01-02 15:04:05"),
+	want.WriteString(fmt.Sprintf(green("STATUS: UP, Availability: 90.00%%, Since: %v, Duration: %v\n"), start.Format("2006-01-02 15:04:05"),
 		time.Since(start).Round(time.Millisecond)))
-	want.WriteString("Unavailable		|	Available again\n")
 	if res != want.String() {
 		t.Errorf("Got: %s\nWant: %s", res, want.String())
 	} else {
@@ -33,19 +32,25 @@ func TestUpDownTransition(t *testing.T) {
 	red := color.FgRed.Render
 	want := strings.Builder{}
 	a := NewAlert(0.8)
-	a.Availability = 70
+	a.Availability = 0.7
 
 	// Goes down
-	a.AlertState++
-
 	downAt := time.Now()
-	a.LastTimeUnavailable = append(a.LastTimeUnavailable, downAt)
+	if !a.Update(downAt, false, "status 500") {
+		t.Fatalf("expected the alert to go down")
+	}
+	a.Availability = 0.6
+	a.Update(downAt.Add(time.Second), false, "status 500")
 	res := a.PrintTest()
 
-	want.WriteString(fmt.Sprintf(red("STATUS: DOWN, Availability: %v, Since: %v, Duration: %v\n"), a.Availability, downAt.Format("2006-01-02 15:04:05"),
+	want.WriteString(fmt.Sprintf(red("STATUS: DOWN, Availability: 60.00%%, Since: %v, Duration: %v\n"), downAt.Format("2006-01-02 15:04:05"),
+		time.Since(downAt).Round(time.Millisecond)))
+	want.WriteString(red("Reason: status 500\n"))
+	want.WriteString("Unavailable		|	Available again		|	Duration	|	Min availability	|	Reason\n")
+	want.WriteString(fmt.Sprintf("%v		ongoing				%v		60.00%%			status 500\n", downAt.Format("2006-01-02 15:04:05"),
 		time.Since(downAt).Round(time.Millisecond)))
-	want.WriteString("Unavailable		|	Available again\n")
-	want.WriteString(fmt.Sprint(downAt.Format("2006-01-02 15:04:05"), "		"))
+	st := a.Stats(a.Since, time.Now())
+	want.WriteString(fmt.Sprintf("Incidents: 1, Downtime: %v, MTTR: 0s, MTBF: %v\n", st.Downtime.Round(time.Millisecond), st.MTBF.Round(time.Millisecond)))
 
 	if res != want.String() {
 		t.Errorf("Got: %s\nWant: %s", res, want.String())
@@ -59,26 +64,27 @@ func TestUpDownUpTransition(t *testing.T) {
 	green := color.FgGreen.Render
 	want := strings.Builder{}
 	a := NewAlert(0.8)
-	a.Availability = 70
+	a.Since = a.Since.Add(-4 * time.Second)
+	a.Availability = 0.7
 
 	// Goes down
-	a.AlertState++
-
-	downAt := time.Now()
-	a.LastTimeUnavailable = append(a.LastTimeUnavailable, downAt)
+	downAt := a.Since.Add(time.Second)
+	a.Update(downAt, false, "timeout")
 
 	// Goes up again
-	upAt := time.Now()
-	a.AlertState--
-	a.LastTimeAvailable = append(a.LastTimeAvailable, upAt)
-	a.Availability = 90
+	upAt := downAt.Add(2 * time.Second)
+	a.Availability = 0.9
+	if !a.Update(upAt, true, "") {
+		t.Fatalf("expected the alert to go up")
+	}
 	res := a.PrintTest()
 
-	want.WriteString(fmt.Sprintf(green("STATUS: UP, Availability: 90, Since: %v, Duration: %v\n"), upAt.Format("2006-01-02 15:04:05"), time.Since(upAt).Round(time.Millisecond)))
+	want.WriteString(fmt.Sprintf(green("STATUS: UP, Availability: 90.00%%, Since: %v, Duration: %v\n"), upAt.Format("2006-01-02 15:04:05"), time.Since(upAt).Round(time.Millisecond)))
 
-	want.WriteString("Unavailable		|	Available again\n")
-	want.WriteString(fmt.Sprint(downAt.Format("2006-01-02 15:04:05"), "		"))
-	want.WriteString(fmt.Sprintf("%v\n", upAt.Format("2006-01-02 15:04:05")))
+	want.WriteString("Unavailable		|	Available again		|	Duration	|	Min availability	|	Reason\n")
+	want.WriteString(fmt.Sprintf("%v		%v		2s		70.00%%			timeout\n", downAt.Format("2006-01-02 15:04:05"), upAt.Format("2006-01-02 15:04:05")))
+	st := a.Stats(a.Since, time.Now())
+	want.WriteString(fmt.Sprintf("Incidents: 1, Downtime: %v, MTTR: 2s, MTBF: %v\n", st.Downtime.Round(time.Millisecond), st.MTBF.Round(time.Millisecond)))
 
 	if res != want.String() {
 		t.Errorf("Got: %s\nWant: %s", res, want.String())
@@ -86,3 +92,42 @@ func TestUpDownUpTransition(t *testing.T) {
 		fmt.Println("Down to Up - OK")
 	}
 }
+
+// Test the statistics of the incidents within a period
+func TestIncidentStats(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	a := NewAlert(0.8)
+	for _, in := range [][2]int{{10, 20}, {50, 60}, {100, 130}} {
+		a.Update(at(in[0]), false, "down")
+		a.Update(at(in[1]), true, "")
+	}
+	// Ongoing since the 170th minute
+	a.Update(at(170), false, "down")
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     IncidentStats
+	}{
+		{"every incident", at(0), at(180), IncidentStats{
+			Incidents: 4,
+			Downtime:  60 * time.Minute,
+			MTTR:      50 * time.Minute / 3,
+			MTBF:      30 * time.Minute,
+		}},
+		{"the parts within the period", at(15), at(55), IncidentStats{
+			Incidents: 2,
+			Downtime:  10 * time.Minute,
+			// Only the first incident was resolved within the period, and counts in full
+			MTTR: 10 * time.Minute,
+			MTBF: 15 * time.Minute,
+		}},
+		{"no incidents", at(20), at(50), IncidentStats{}},
+	}
+	for _, tt := range tests {
+		if got := a.Stats(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+)
+
+type CertificateState uint32
+
+// States of the certificate FSM
+// Valid -> (expires in less than the threshold) -> Expiring
+// Valid/Expiring -> (expired, untrusted or hostname mismatch) -> Invalid
+// and back to Valid when a renewed certificate is found
+const (
+	CertificateValid CertificateState = iota
+	CertificateExpiring
+	CertificateInvalid
+)
+
+func (s CertificateState) String() string {
+	switch s {
+	case CertificateValid:
+		return "VALID"
+	case CertificateExpiring:
+		return "EXPIRING"
+	case CertificateInvalid:
+		return "INVALID"
+	}
+	return "UNKNOWN"
+}
+
+// A CertificateTransition is stored every time the alert changes state
+type CertificateTransition struct {
+	Time   time.Time
+	State  CertificateState
+	Reason string
+}
+
+// CertificateAlert fires when the certificate of a website is about to expire
+// or cannot be trusted, independently of the availability of the website
+type CertificateAlert struct {
+	State CertificateState
+
+	// How long before the expiry the alert fires
+	ExpiryThreshold time.Duration
+
+	// The reason of the current state
+	Reason string
+
+	// Every state change of the alert
+	Transitions []CertificateTransition
+}
+
+func NewCertificateAlert(expiryThreshold time.Duration) *CertificateAlert {
+	return &CertificateAlert{
+		State:           CertificateValid,
+		ExpiryThreshold: expiryThreshold,
+		Transitions:     make([]CertificateTransition, 0),
+	}
+}
+
+// Returns a copy of the alert that is not affected by later updates
+func (c *CertificateAlert) Clone() *CertificateAlert {
+	res := *c
+	res.Transitions = append(make([]CertificateTransition, 0, len(c.Transitions)), c.Transitions...)
+	return &res
+}
+
+// Updates the state of the alert, given the latest inspection of the certificate.
+// It returns true when the state changed
+func (c *CertificateAlert) Update(notAfter time.Time, trusted, hostnameMatch bool, verifyErr string) bool {
+	now := time.Now()
+	state := CertificateValid
+	reason := ""
+	switch {
+	case now.After(notAfter):
+		state = CertificateInvalid
+		reason = fmt.Sprintf("expired on %v", notAfter.Format("2006-01-02 15:04:05"))
+	case !hostnameMatch:
+		state = CertificateInvalid
+		reason = "hostname mismatch"
+	case !trusted:
+		state = CertificateInvalid
+		reason = "untrusted chain"
+		if verifyErr != "" {
+			reason = fmt.Sprintf("%s: %s", reason, verifyErr)
+		}
+	case notAfter.Sub(now) < c.ExpiryThreshold:
+		state = CertificateExpiring
+		reason = fmt.Sprintf("expires in %d days", int(notAfter.Sub(now).Hours()/24))
+	}
+	c.Reason = reason
+	if state == c.State {
+		return false
+	}
+	c.State = state
+	c.Transitions = append(c.Transitions, CertificateTransition{
+		Time:   now,
+		State:  state,
+		Reason: reason,
+	})
+	return true
+}
+
+// Returns the current state of the alert and its history
+func (c *CertificateAlert) PrintTest() string {
+	var res strings.Builder
+	switch c.State {
+	case CertificateValid:
+		res.WriteString(color.FgGreen.Render("CERTIFICATE: VALID\n"))
+	case CertificateExpiring:
+		res.WriteString(color.FgYellow.Render(fmt.Sprintf("CERTIFICATE: EXPIRING, %s\n", c.Reason)))
+	default:
+		res.WriteString(color.FgRed.Render(fmt.Sprintf("CERTIFICATE: INVALID, %s\n", c.Reason)))
+	}
+	for _, t := range c.Transitions {
+		res.WriteString(fmt.Sprintf("%v		%v %s\n", t.Time.Format("2006-01-02 15:04:05"), t.State, t.Reason))
+	}
+	return res.String()
+}
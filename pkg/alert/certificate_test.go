@@ -0,0 +1,34 @@
+package alert
+
+import (
+	"testing"
+	"time"
+)
+
+// Test the transitions of the certificate alert
+func TestCertificateAlert(t *testing.T) {
+	c := NewCertificateAlert(14 * 24 * time.Hour)
+	steps := []struct {
+		notAfter      time.Time
+		trusted       bool
+		hostnameMatch bool
+		want          CertificateState
+		changed       bool
+	}{
+		{time.Now().Add(90 * 24 * time.Hour), true, true, CertificateValid, false},
+		{time.Now().Add(10 * 24 * time.Hour), true, true, CertificateExpiring, true},
+		{time.Now().Add(-time.Hour), true, true, CertificateInvalid, true},
+		{time.Now().Add(90 * 24 * time.Hour), true, false, CertificateInvalid, false},
+		{time.Now().Add(90 * 24 * time.Hour), true, true, CertificateValid, true},
+		{time.Now().Add(90 * 24 * time.Hour), false, true, CertificateInvalid, true},
+	}
+	for i, s := range steps {
+		changed := c.Update(s.notAfter, s.trusted, s.hostnameMatch, "")
+		if c.State != s.want || changed != s.changed {
+			t.Errorf("step %d: got %v (changed %v), want %v (changed %v)", i, c.State, changed, s.want, s.changed)
+		}
+	}
+	if len(c.Transitions) != 4 {
+		t.Errorf("got %d transitions, want 4", len(c.Transitions))
+	}
+}
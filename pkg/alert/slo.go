@@ -0,0 +1,101 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gookit/color"
+)
+
+type SLOState uint32
+
+// States of the burn-rate FSM
+// OK -> (the error budget burns faster than a threshold in both windows of a rule) -> Burning
+// Burning -> (no rule exceeds its threshold) -> OK
+const (
+	SLOOK SLOState = iota
+	SLOBurning
+)
+
+func (s SLOState) String() string {
+	switch s {
+	case SLOOK:
+		return "OK"
+	case SLOBurning:
+		return "BURNING"
+	}
+	return "UNKNOWN"
+}
+
+// An SLOTransition is stored every time the alert changes state
+type SLOTransition struct {
+	Time   time.Time
+	State  SLOState
+	Reason string
+}
+
+// SLOAlert fires when the error budget of a service level objective
+// is consumed too fast, independently of the availability alert
+type SLOAlert struct {
+	// The name of the objective
+	Name  string
+	State SLOState
+
+	// The rule that fired, while burning
+	Reason string
+
+	// Every state change of the alert
+	Transitions []SLOTransition
+}
+
+func NewSLOAlert(name string) *SLOAlert {
+	return &SLOAlert{
+		Name:        name,
+		State:       SLOOK,
+		Transitions: make([]SLOTransition, 0),
+	}
+}
+
+// Returns a copy of the alert that is not affected by later updates
+func (a *SLOAlert) Clone() *SLOAlert {
+	c := *a
+	c.Transitions = append(make([]SLOTransition, 0, len(a.Transitions)), a.Transitions...)
+	return &c
+}
+
+// Updates the state of the alert at the given time, given whether any burn-rate rule is exceeded.
+// It returns true when the state changed
+func (a *SLOAlert) Update(t time.Time, burning bool, reason string) bool {
+	state := SLOOK
+	if burning {
+		state = SLOBurning
+	} else {
+		reason = ""
+	}
+	a.Reason = reason
+	if state == a.State {
+		return false
+	}
+	a.State = state
+	a.Transitions = append(a.Transitions, SLOTransition{
+		Time:   t,
+		State:  state,
+		Reason: reason,
+	})
+	return true
+}
+
+// Returns the current state of the alert and its history
+func (a *SLOAlert) PrintTest() string {
+	var res strings.Builder
+	if a.State == SLOBurning {
+		res.WriteString(color.FgRed.Render(fmt.Sprintf("SLO %s: BURNING, %s\n", a.Name, a.Reason)))
+	} else {
+		res.WriteString(color.FgGreen.Render(fmt.Sprintf("SLO %s: OK\n", a.Name)))
+	}
+	for _, t := range a.Transitions {
+		res.WriteString(fmt.Sprintf("%v		%v %s\n", t.Time.Format("2006-01-02 15:04:05"), t.State, t.Reason))
+	}
+	return res.String()
+}
@@ -0,0 +1,79 @@
+package info
+
+import (
+	"fmt"
+	"time"
+)
+
+// The level of user satisfaction of a single response
+type apdexLevel uint8
+
+const (
+	frustrated apdexLevel = iota
+	tolerating
+	satisfied
+)
+
+// ApdexResult is the Apdex score of a window, together with the counts it is calculated from
+type ApdexResult struct {
+	// (satisfied + tolerating / 2) / responses, between 0 and 1
+	Score      float64       `json:"score"`
+	Threshold  time.Duration `json:"threshold"`
+	Satisfied  int           `json:"satisfied"`
+	Tolerating int           `json:"tolerating"`
+	// Including the failed responses
+	Frustrated int `json:"frustrated"`
+}
+
+// Sets the Apdex threshold T of the window: successful responses up to T are satisfying,
+// up to 4T tolerable, and the rest frustrating. When the window has an alert, it also fires
+// once the score falls below alertBelow (0 disables it).
+// The responses already in the window keep the level they were given
+func (i *Info) SetApdex(threshold time.Duration, alertBelow float64) {
+	i.apdexThreshold = threshold
+	if i.hasAlert {
+		i.Alert.ApdexThreshold = alertBelow
+	}
+}
+
+// Classifies a response according to the Apdex threshold
+func (i *Info) apdexLevel(res *Response) apdexLevel {
+	switch {
+	case !res.Success:
+		return frustrated
+	case res.Delay <= i.apdexThreshold:
+		return satisfied
+	case res.Delay <= 4*i.apdexThreshold:
+		return tolerating
+	}
+	return frustrated
+}
+
+// Returns the Apdex score of the window, nil when no threshold is set
+func (i *Info) apdex() *ApdexResult {
+	if i.apdexThreshold <= 0 || i.TotalResponses == 0 {
+		return nil
+	}
+	return &ApdexResult{
+		Score:      (float64(i.satisfied) + float64(i.tolerating)/2) / float64(i.TotalResponses),
+		Threshold:  i.apdexThreshold,
+		Satisfied:  i.satisfied,
+		Tolerating: i.tolerating,
+		Frustrated: i.TotalResponses - i.satisfied - i.tolerating,
+	}
+}
+
+// Counts a response in the score, or removes it when n is -1
+func (i *Info) countApdex(level apdexLevel, n int) {
+	switch level {
+	case satisfied:
+		i.satisfied += n
+	case tolerating:
+		i.tolerating += n
+	}
+}
+
+// Formats the score, e.g. "apdex 0.94 [T=500ms]"
+func (a *ApdexResult) String() string {
+	return fmt.Sprintf("apdex %.2f [T=%v]", a.Score, a.Threshold)
+}
@@ -0,0 +1,43 @@
+package info
+
+import (
+	"crypto/x509"
+	"time"
+)
+
+// Certificate describes the leaf certificate presented by a website
+type Certificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dnsNames"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	// Whether the chain is signed by a trusted authority
+	Trusted bool `json:"trusted"`
+	// Whether the certificate is valid for the host of the website
+	HostnameMatch bool `json:"hostnameMatch"`
+	// The verification error, empty when the certificate is valid
+	Error string `json:"error,omitempty"`
+	// The time the certificate was inspected
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+func NewCertificate(leaf *x509.Certificate) *Certificate {
+	return &Certificate{
+		Subject:   leaf.Subject.String(),
+		Issuer:    leaf.Issuer.String(),
+		DNSNames:  leaf.DNSNames,
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+		CheckedAt: time.Now(),
+	}
+}
+
+// Returns the remaining time until the certificate expires
+func (c *Certificate) ExpiresIn() time.Duration {
+	return c.NotAfter.Sub(c.CheckedAt)
+}
+
+func (c *Certificate) Expired() bool {
+	return c.CheckedAt.After(c.NotAfter) || c.CheckedAt.Before(c.NotBefore)
+}
@@ -0,0 +1,157 @@
+package info
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// The upper bounds of the histogram buckets, when none are configured for a window.
+// They are the default buckets of the Prometheus client libraries
+var DefaultBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram counts the response times of a window in buckets,
+// in the cumulative shape of a Prometheus histogram
+type Histogram struct {
+	// The upper bounds of the buckets, the last bucket (+Inf) has none
+	Bounds []time.Duration `json:"bounds"`
+	// The number of responses up to each bound, followed by the number of every response
+	Counts []int         `json:"counts"`
+	Sum    time.Duration `json:"sum"`
+}
+
+// Returns the number of responses in each bucket alone, instead of up to its bound
+func (h *Histogram) Buckets() []int {
+	res := make([]int, len(h.Counts))
+	for j, c := range h.Counts {
+		res[j] = c
+		if j > 0 {
+			res[j] -= h.Counts[j-1]
+		}
+	}
+	return res
+}
+
+// Checks that the bounds of histogram buckets are positive and increasing
+func ValidateBuckets(bounds []time.Duration) error {
+	for j, b := range bounds {
+		if b <= 0 {
+			return fmt.Errorf("invalid bucket %v, must be positive", b)
+		}
+		if j > 0 && b <= bounds[j-1] {
+			return fmt.Errorf("invalid bucket %v, must be greater than %v", b, bounds[j-1])
+		}
+	}
+	return nil
+}
+
+// Sets the upper bounds of the histogram buckets of the window.
+// It must be called before the first update
+func (i *Info) SetBuckets(bounds []time.Duration) error {
+	if err := ValidateBuckets(bounds); err != nil {
+		return err
+	}
+	i.Buckets = bounds
+	i.bucketCounts = make([]int, len(bounds)+1)
+	return nil
+}
+
+// Returns the index of the bucket of a response time
+func (i *Info) bucket(d time.Duration) int {
+	return sort.Search(len(i.Buckets), func(j int) bool { return d <= i.Buckets[j] })
+}
+
+// Returns the absolute difference between the response time and the one
+// of the previous successful response, and whether there is a previous one
+func (i *Info) jitter(res *Response) (time.Duration, bool) {
+	if i.lastSuccess == nil {
+		return 0, false
+	}
+	d := res.Delay - i.lastSuccess.Delay
+	if d < 0 {
+		d = -d
+	}
+	return d, true
+}
+
+// Adds a response time to the distribution of the window, or removes it when n is -1
+func (i *Info) countDistribution(d time.Duration, e entry, n int) {
+	i.bucketCounts[i.bucket(d)] += n
+	i.sumDelays += float64(n) * float64(d)
+	i.sumSquares += float64(n) * float64(d) * float64(d)
+	if e.paired {
+		i.jitterSum += time.Duration(n) * e.jitter
+		i.jitterCount += n
+	}
+}
+
+// Returns the minimum response time of the window, zero without successful responses
+func (i *Info) min() time.Duration {
+	if i.slices == nil {
+		if len(i.MinResponsesList) == 0 {
+			return 0
+		}
+		return i.MinResponsesList[0]
+	}
+	min := time.Duration(math.MaxInt64)
+	for _, s := range i.slices {
+		if s.successful > 0 && s.min < min {
+			min = s.min
+		}
+	}
+	if min == math.MaxInt64 {
+		return 0
+	}
+	return min
+}
+
+// Returns the standard deviation of the successful response times of the window
+func (i *Info) stdDev() time.Duration {
+	n := float64(i.SuccessfulResponses)
+	if n == 0 {
+		return 0
+	}
+	mean := i.sumDelays / n
+	variance := i.sumSquares/n - mean*mean
+	if variance <= 0 {
+		// Rounding errors of equal response times
+		return 0
+	}
+	return time.Duration(math.Sqrt(variance))
+}
+
+// Returns the mean absolute difference between consecutive response times
+func (i *Info) meanJitter() time.Duration {
+	if i.jitterCount == 0 {
+		return 0
+	}
+	return i.jitterSum / time.Duration(i.jitterCount)
+}
+
+// Returns the histogram of the response times of the window
+func (i *Info) histogram() *Histogram {
+	h := &Histogram{
+		Bounds: i.Buckets,
+		Counts: make([]int, len(i.bucketCounts)),
+		Sum:    time.Duration(i.sumDelays),
+	}
+	cum := 0
+	for j, c := range i.bucketCounts {
+		cum += c
+		h.Counts[j] = cum
+	}
+	return h
+}
@@ -0,0 +1,37 @@
+package info
+
+// FailureClass categorizes why a request failed
+type FailureClass string
+
+const (
+	// The request succeeded
+	NoFailure FailureClass = ""
+	// The hostname could not be resolved
+	DNSFailure FailureClass = "dns_failure"
+	// The server actively refused the connection
+	ConnectionRefused FailureClass = "connection_refused"
+	// The connection was reset or closed by the peer
+	ConnectionReset FailureClass = "connection_reset"
+	// Timeouts, depending on the phase of the request that did not complete in time
+	DNSTimeout      FailureClass = "dns_timeout"
+	ConnectTimeout  FailureClass = "connect_timeout"
+	TLSTimeout      FailureClass = "tls_timeout"
+	ResponseTimeout FailureClass = "response_timeout"
+	// The TLS handshake or the verification of the certificate failed
+	TLSError FailureClass = "tls_error"
+	// The redirect policy of the website was violated
+	TooManyRedirects FailureClass = "too_many_redirects"
+	// A response was received, but did not pass the assertions of the website
+	AssertionFailure FailureClass = "assertion_failure"
+	// Any other error
+	OtherFailure FailureClass = "other"
+)
+
+// Reports whether the failure happened because a phase did not complete in time
+func (f FailureClass) IsTimeout() bool {
+	switch f {
+	case DNSTimeout, ConnectTimeout, TLSTimeout, ResponseTimeout:
+		return true
+	}
+	return false
+}
@@ -2,61 +2,155 @@ package info
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/iwita/monitoring-website-stats/pkg/alert"
-	"github.com/iwita/monitoring-website-stats/pkg/heap"
 )
 
 type Result struct {
-	Max          time.Duration
-	Average      time.Duration
-	Percentile   time.Duration
-	Availability float64
-	StatusCodes  string
+	Max     time.Duration `json:"max"`
+	Average time.Duration `json:"average"`
+	Min     time.Duration `json:"min"`
+	// The standard deviation of the successful response times
+	StdDev time.Duration `json:"stddev"`
+	// The mean absolute difference between consecutive successful response times
+	Jitter    time.Duration `json:"jitter"`
+	Histogram *Histogram    `json:"histogram"`
+	// The configured percentiles of the response times, in the configured order
+	Percentiles  []PercentileResult `json:"percentiles"`
+	Availability float64            `json:"availability"`
+	// The number of responses in the window
+	Responses int `json:"responses"`
+	// The user satisfaction score, when an Apdex threshold is set
+	Apdex *ApdexResult `json:"apdex,omitempty"`
+	// Percentage of the window covered by samples, the rest are gaps counted as unavailable
+	Coverage float64 `json:"coverage"`
+	// The responses per status code and per class, and the error rate
+	StatusCodes *StatusResult `json:"statusCodes"`
+	// Number of failed responses per failure reason
+	Failures map[string]int `json:"failures,omitempty"`
+	// Number of failed responses per failure class
+	FailureClasses map[FailureClass]int `json:"failureClasses,omitempty"`
+	// Breakdown of the response time into the phases of the requests
+	Timings *TimingResult `json:"timings,omitempty"`
 }
 
 type Response struct {
-	Delay  time.Duration
+	// The time the check started
+	Time  time.Time
+	Delay time.Duration
+	// The status code of the response, 0 when no response was received
 	Status int
+	// The class of the failure, empty when successful
+	Failure FailureClass
+	// Whether the response passed every check of the website
+	Success bool
+	// Describes why the response failed, empty when successful
+	Reason string
+	// Phases of the request, nil when no response was received
+	Timing *Timing
+	// The certificate presented by the website, nil for plain http
+	Certificate *Certificate
+	// Every request of the chain, when redirects were followed
+	Redirects []Redirect
 }
 
 // Info is the main type of this package.
 // It inlcudes both raw and processed information about a specific time window
 // specified by 'Duration'
 // *Additionally, some of the Info types may include an Alert
+//
+// Responses are evicted once they are older than the window. Each response
+// covers the time since the previous one, so that availability is calculated
+// over the wall-clock window, and the time no responses arrived counts as unavailable
 type Info struct {
-	MaxResponse         time.Duration
-	AverageResponse     time.Duration
-	SumResponses        time.Duration
-	ResponsesList       []*Response
-	MaxResponsesList    []time.Duration
-	Length              int
-	Duration            time.Duration
+	MaxResponse      time.Duration
+	AverageResponse  time.Duration
+	SumResponses     time.Duration
+	ResponsesList    []*Response
+	MaxResponsesList []time.Duration
+	Duration         time.Duration
+	// The percentiles of the response times calculated for the window
+	Percentiles []float64
+	// The expected time between two responses
+	Interval            time.Duration
 	StatusCodesCount    map[int]int
+	FailureReasons      map[string]int
+	FailureClassesCount map[FailureClass]int
 	SuccessfulResponses int
 	TotalResponses      int
-	hasAlert            bool
-	Alert               *alert.Alert
+	// The reason of the most recent failed response
+	LastFailure string
+	hasAlert    bool
+	Alert       *alert.Alert
+
+	// What is derived from each response when it is added, in the order of ResponsesList
+	entries []entry
+	// The time covered by all the responses, and by the successful ones
+	coveredTime   time.Duration
+	availableTime time.Duration
+	// The time of the first response ever, before which the window is not counted
+	since time.Time
+	// The most recent response, which the time covered by the next one is counted from
+	last *Response
+	// The most recent successful response, which the jitter of the next one is counted from
+	lastSuccess *Response
+
+	// When set by UseSketch, the responses are summarized in slices instead of ResponsesList
+	slices   []*slice
+	accuracy float64
+
+	// The Apdex threshold, and the number of satisfied and tolerating responses
+	apdexThreshold time.Duration
+	satisfied      int
+	tolerating     int
+
+	// The response time objective, and the number of responses that met the objective
+	latencyObjective time.Duration
+	good             int
+
+	// The upper bounds of the histogram buckets, and the number of responses in each
+	Buckets      []time.Duration
+	bucketCounts []int
+	// The minimum in the order they occur, as MaxResponsesList
+	MinResponsesList []time.Duration
+	// The sum of the successful response times and of their squares, in nanoseconds
+	sumDelays, sumSquares float64
+	// The sum and number of the differences between consecutive response times
+	jitterSum   time.Duration
+	jitterCount int
+	// The sums of the regression of the successful response times over time
+	regression regression
+}
+
+// entry keeps what has to be removed along with a response, once it leaves the window
+type entry struct {
+	// The time covered by the response
+	covered time.Duration
+	apdex   apdexLevel
+	// Whether the response met the objective of the window
+	good bool
+	// The difference from the previous response time, when there is a previous response
+	jitter time.Duration
+	paired bool
 }
 
+// Creates the statistics of a time window. A zero duration means unlimited
 func NewInfo(duration, interval time.Duration, hasAlert bool) *Info {
-	var length int
-	if duration == time.Duration(0) {
-		// we have the unlimited case
-		length = -1
-	} else {
-		length = int(duration / interval)
-	}
 	i := &Info{
 		MaxResponse:         0,
 		ResponsesList:       make([]*Response, 0),
 		MaxResponsesList:    make([]time.Duration, 0),
 		SumResponses:        time.Duration(0) * time.Millisecond,
 		Duration:            duration,
-		Length:              length,
+		Percentiles:         DefaultPercentiles,
+		Buckets:             DefaultBuckets,
+		bucketCounts:        make([]int, len(DefaultBuckets)+1),
+		MinResponsesList:    make([]time.Duration, 0),
+		Interval:            interval,
 		StatusCodesCount:    make(map[int]int, 0),
+		FailureReasons:      make(map[string]int, 0),
+		FailureClassesCount: make(map[FailureClass]int, 0),
 		SuccessfulResponses: 0,
 		TotalResponses:      0,
 		hasAlert:            false,
@@ -69,29 +163,52 @@ func NewInfo(duration, interval time.Duration, hasAlert bool) *Info {
 }
 
 // Updates the information stored in a predefined time window
-func (i *Info) Update(status int, elapsedTime time.Duration) {
+func (i *Info) Update(res *Response) {
+	elapsedTime := res.Delay
+	if res.Time.IsZero() {
+		res.Time = time.Now()
+	}
 	// 1. Delete the outdated responses if any
-	if i.TotalResponses == i.Length {
-		i.TotalResponses--
-		responseToBeDeleted := i.ResponsesList[0]
-		i.ResponsesList = i.ResponsesList[1:]
-		i.SumResponses -= responseToBeDeleted.Delay
-		i.StatusCodesCount[responseToBeDeleted.Status]--
-		if responseToBeDeleted.Status >= 200 && responseToBeDeleted.Status < 300 {
-			i.SuccessfulResponses--
-		}
-		// Update the maximum in the respective Deque
-		if responseToBeDeleted.Delay == i.MaxResponsesList[0] {
-			i.MaxResponsesList = i.MaxResponsesList[1:]
-		}
+	i.expire(res.Time)
+
+	e := entry{apdex: i.apdexLevel(res), good: i.meetsObjective(res)}
+	if i.last == nil {
+		i.since = res.Time
+	} else {
+		e.covered = i.coverage(i.last, res)
+	}
+	if res.Success {
+		e.jitter, e.paired = i.jitter(res)
+		i.lastSuccess = res
+	}
+	i.last = res
+	covered := e.covered
+	i.countApdex(e.apdex, 1)
+	if e.good {
+		i.good++
+	}
+	// The delay of a failed response is that of a timeout, or of no response at all
+	if res.Success {
+		i.countDistribution(elapsedTime, e, 1)
+		i.regression.count(i.since, res, 1)
 	}
 
 	// 2. Push a new item
-
-	// 2.1 Update the maximum in the helping data structure
-	if i.TotalResponses == 0 {
+	if i.slices != nil {
+		i.addToSlice(res, e)
+	} else if res.Success && i.SuccessfulResponses == 0 {
+		// 2.1 Update the maximum and the minimum in the helping data structures
 		i.MaxResponsesList = append(i.MaxResponsesList, elapsedTime)
-	} else {
+		i.MinResponsesList = append(i.MinResponsesList, elapsedTime)
+	} else if res.Success {
+		// Likewise, keep the minimum elements in the order they occur
+		for j, el := range i.MinResponsesList {
+			if el > elapsedTime {
+				i.MinResponsesList = i.MinResponsesList[:j]
+				break
+			}
+		}
+		i.MinResponsesList = append(i.MinResponsesList, elapsedTime)
 		// Update the max in the helping data structure
 		for j, el := range i.MaxResponsesList {
 			// remove all elements smaller than current
@@ -109,19 +226,31 @@ func (i *Info) Update(status int, elapsedTime time.Duration) {
 
 	// 2.2 Add info about the new item
 
-	i.StatusCodesCount[status]++
-	if status >= 200 && status < 300 {
+	if res.Status != 0 {
+		i.StatusCodesCount[res.Status]++
+	}
+	if res.Success {
 		i.SuccessfulResponses++
 		// Keep the sum of the delays in the time window, in order to
 		// calculate the average in constant time
 		i.SumResponses += elapsedTime
 
+	} else {
+		if i.countsReasons() {
+			i.FailureReasons[res.Reason]++
+		}
+		i.FailureClassesCount[res.Failure]++
+		i.LastFailure = res.Reason
+	}
+	i.coveredTime += covered
+	if res.Success {
+		i.availableTime += covered
 	}
 	i.TotalResponses++
-	i.ResponsesList = append(i.ResponsesList, &Response{
-		Delay:  elapsedTime,
-		Status: status,
-	})
+	if i.slices == nil {
+		i.entries = append(i.entries, e)
+		i.ResponsesList = append(i.ResponsesList, res)
+	}
 
 	// Moved upwards only in case of successful response
 	//i.SumResponses += elapsedTime
@@ -131,27 +260,180 @@ func (i *Info) Update(status int, elapsedTime time.Duration) {
 	}
 }
 
+// The period in which a late response is not counted as a gap, in intervals
+const gapTolerance = 1.5
+
+// Returns the time covered by a response, i.e. the time since the previous one.
+// It is limited to the duration of the previous check plus the interval,
+// the rest is a gap in which no check was performed
+func (i *Info) coverage(prev, res *Response) time.Duration {
+	covered := res.Time.Sub(prev.Time)
+	if limit := prev.Delay + time.Duration(gapTolerance*float64(i.Interval)); i.Interval > 0 && covered > limit {
+		covered = limit
+	}
+	if covered < 0 {
+		return 0
+	}
+	return covered
+}
+
+// Removes the responses that are older than the time window
+func (i *Info) expire(now time.Time) {
+	if i.Duration == 0 {
+		// we have the unlimited case
+		return
+	}
+	cutoff := now.Add(-i.Duration)
+	if i.slices != nil {
+		for len(i.slices) > 0 && !i.slices[0].end.After(cutoff) {
+			i.evictSlice()
+		}
+		return
+	}
+	for i.TotalResponses > 0 && i.ResponsesList[0].Time.Before(cutoff) {
+		i.evictOldest()
+	}
+}
+
+// Removes the oldest response from the time window
+func (i *Info) evictOldest() {
+	i.TotalResponses--
+	responseToBeDeleted := i.ResponsesList[0]
+	i.ResponsesList = i.ResponsesList[1:]
+	i.coveredTime -= i.entries[0].covered
+	if responseToBeDeleted.Success {
+		i.availableTime -= i.entries[0].covered
+	}
+	i.countApdex(i.entries[0].apdex, -1)
+	if i.entries[0].good {
+		i.good--
+	}
+	e := i.entries[0]
+	i.entries = i.entries[1:]
+	if responseToBeDeleted.Success {
+		i.countDistribution(responseToBeDeleted.Delay, e, -1)
+		// The jitter of the next successful response involves the one leaving the window
+		for j, r := range i.ResponsesList {
+			if !r.Success {
+				continue
+			}
+			if i.entries[j].paired {
+				i.jitterSum -= i.entries[j].jitter
+				i.jitterCount--
+				i.entries[j].paired = false
+			}
+			break
+		}
+	}
+	if responseToBeDeleted.Status != 0 {
+		i.StatusCodesCount[responseToBeDeleted.Status]--
+		if i.StatusCodesCount[responseToBeDeleted.Status] == 0 {
+			delete(i.StatusCodesCount, responseToBeDeleted.Status)
+		}
+	}
+	if responseToBeDeleted.Success {
+		i.SuccessfulResponses--
+		i.SumResponses -= responseToBeDeleted.Delay
+		i.regression.count(i.since, responseToBeDeleted, -1)
+	} else {
+		i.FailureReasons[responseToBeDeleted.Reason]--
+		if i.FailureReasons[responseToBeDeleted.Reason] == 0 {
+			delete(i.FailureReasons, responseToBeDeleted.Reason)
+		}
+		i.FailureClassesCount[responseToBeDeleted.Failure]--
+		if i.FailureClassesCount[responseToBeDeleted.Failure] == 0 {
+			delete(i.FailureClassesCount, responseToBeDeleted.Failure)
+		}
+	}
+	// Update the maximum in the respective Deque
+	if !responseToBeDeleted.Success {
+		return
+	}
+	if responseToBeDeleted.Delay == i.MaxResponsesList[0] {
+		i.MaxResponsesList = i.MaxResponsesList[1:]
+	}
+	if responseToBeDeleted.Delay == i.MinResponsesList[0] {
+		i.MinResponsesList = i.MinResponsesList[1:]
+	}
+}
+
+// Reports whether the failures are counted per reason, besides their class.
+// The reasons often include e.g. a port or an actual value, so that a window
+// without limit, from which they are never removed, counts only the classes
+func (i *Info) countsReasons() bool {
+	return i.Duration > 0
+}
+
+// Returns the time of the first response ever, zero before any response
+func (i *Info) Since() time.Time {
+	return i.since
+}
+
+// Adapts the time window to a new interval between the responses.
+// The responses already stored keep the time they cover
+func (i *Info) Resize(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	i.Interval = interval
+}
+
+// Returns the ratio of the time window that the website was available,
+// and the ratio that was covered by responses, up to the most recent response.
+// The part of the window before the first response ever is not counted
+func (i *Info) availability() (available, covered float64) {
+	if i.TotalResponses == 0 {
+		return 0, 0
+	}
+	end := i.last.Time
+	start := i.since
+	if i.slices != nil {
+		// The window begins with the time covered by its oldest slice
+		if from := i.slices[0].from; from.After(start) {
+			start = from
+		}
+	} else if i.Duration > 0 && end.Add(-i.Duration).After(start) {
+		start = end.Add(-i.Duration)
+	}
+	span := end.Sub(start)
+	if span <= 0 {
+		// A single response covers no time yet
+		return float64(i.SuccessfulResponses) / float64(i.TotalResponses), 1
+	}
+	coveredTime, availableTime := i.coveredTime, i.availableTime
+	// The oldest response may cover time before the start of the window,
+	// whereas the slices only cover time after it
+	if i.slices == nil {
+		if excess := i.entries[0].covered - i.ResponsesList[0].Time.Sub(start); excess > 0 {
+			coveredTime -= excess
+			if i.ResponsesList[0].Success {
+				availableTime -= excess
+			}
+		}
+	}
+	return float64(availableTime) / float64(span), float64(coveredTime) / float64(span)
+}
+
 // Updates the alert's values
 // More specifically,
-// 1. Stores the current availability in the array
-// 2. If the current state is available, and needs to change, it stores the current time
-//    and moves to unavailable state.
-//    Else if the current state is unavailable, and needs to change, it stores the current time
-//    and moves back to the available state.
+//  1. Stores the current availability (and Apdex score)
+//  2. If the current state is available, and needs to change, it opens an incident
+//     and moves to unavailable state.
+//     Else if the current state is unavailable, and needs to change, it closes the incident
+//     and moves back to the available state.
 func (i *Info) UpdateAlert() {
-	i.Alert.Availability = float64(float64(i.SuccessfulResponses) / float64(i.TotalResponses))
-	switch i.Alert.AlertState {
-	case alert.Available:
-		if i.Alert.Availability < i.Alert.Threshold {
-			i.Alert.LastTimeUnavailable = append(i.Alert.LastTimeUnavailable, time.Now())
-			i.Alert.AlertState++
-		}
-	case alert.Unavailable:
-		if i.Alert.Availability >= i.Alert.Threshold {
-			i.Alert.LastTimeAvailable = append(i.Alert.LastTimeAvailable, time.Now())
-			i.Alert.AlertState--
+	i.Alert.Availability, _ = i.availability()
+	healthy := i.Alert.Availability >= i.Alert.Threshold
+	reason := i.LastFailure
+	if apdex := i.apdex(); apdex != nil && i.Alert.ApdexThreshold > 0 {
+		i.Alert.Apdex = apdex.Score
+		if healthy && apdex.Score < i.Alert.ApdexThreshold {
+			healthy = false
+			reason = fmt.Sprintf("apdex %.2f below %.2f", apdex.Score, i.Alert.ApdexThreshold)
 		}
 	}
+	// The incidents are timed by the checks, rather than by when they were processed
+	i.Alert.Update(i.last.Time, healthy, reason)
 }
 
 // Prints the information stored
@@ -160,16 +442,17 @@ func (i *Info) PrintInfo() {
 		fmt.Println("Metrics currently unavailable")
 		return
 	}
-	// Calculate the 90th percentile of the responses time
-	percentile := get90thPercentile(i.ResponsesList)
-
-	average := time.Duration(int(i.SumResponses) / i.TotalResponses)
-	max := i.MaxResponsesList[0]
-	fmt.Printf("(Average/Max/90th percentile) response time: (%v/%v/%v)\n", average, max, percentile)
-	for key, val := range i.StatusCodesCount {
-		fmt.Printf("Status %v => %v\n", key, val)
+	average, _ := i.Mean()
+	max := i.max()
+	fmt.Printf("(Average/Max) response time: (%v/%v)\n", average, max)
+	for _, p := range i.percentiles() {
+		fmt.Printf("%s response time: %v\n", p.Name(), p.Value)
+	}
+	for _, c := range i.statusResult().Codes {
+		fmt.Printf("Status %v => %v\n", c.Code, c.Count)
 	}
-	fmt.Printf("Availability: %v%% \n", i.SuccessfulResponses*100/i.TotalResponses)
+	available, _ := i.availability()
+	fmt.Printf("Availability: %v%% \n", available*100)
 }
 
 func (i *Info) GetResult() *Result {
@@ -178,37 +461,73 @@ func (i *Info) GetResult() *Result {
 		return nil
 	}
 
-	// Calculate the 90th percentile of the responses time
-	result.Percentile = get90thPercentile(i.ResponsesList).Round(time.Millisecond)
-	result.Average = time.Duration(int(i.SumResponses) / i.TotalResponses).Round(time.Millisecond)
-	result.Max = i.MaxResponsesList[0].Round(time.Millisecond)
+	// Calculate the percentiles of the responses time
+	result.Percentiles = i.percentiles()
+	for j := range result.Percentiles {
+		result.Percentiles[j].Value = result.Percentiles[j].Value.Round(time.Millisecond)
+	}
+	average, _ := i.Mean()
+	result.Average = average.Round(time.Millisecond)
+	result.Max = i.max().Round(time.Millisecond)
+	result.Min = i.min().Round(time.Millisecond)
+	result.StdDev = i.stdDev().Round(time.Microsecond)
+	result.Jitter = i.meanJitter().Round(time.Microsecond)
+	result.Histogram = i.histogram()
 
-	temp := strings.Builder{}
-	for key, val := range i.StatusCodesCount {
-		fmt.Fprintf(&temp, "status %v => %v\n", key, val)
+	result.StatusCodes = i.statusResult()
+	if len(i.FailureReasons) > 0 {
+		result.Failures = make(map[string]int, len(i.FailureReasons))
+		for reason, count := range i.FailureReasons {
+			result.Failures[reason] = count
+		}
+	}
+	if len(i.FailureClassesCount) > 0 {
+		result.FailureClasses = make(map[FailureClass]int, len(i.FailureClassesCount))
+		for class, count := range i.FailureClassesCount {
+			result.FailureClasses[class] = count
+		}
+	}
+	available, covered := i.availability()
+	result.Responses = i.TotalResponses
+	result.Apdex = i.apdex()
+	result.Availability = available * 100
+	result.Coverage = covered * 100
+	if i.slices != nil {
+		result.Timings = i.slicesTimingResult()
+	} else {
+		result.Timings = getTimingResult(i.ResponsesList)
 	}
-	result.StatusCodes = temp.String()
-	result.Availability = (float64(i.SuccessfulResponses) * 100 / float64(i.TotalResponses))
 	return result
 }
 
-func get90thPercentile(responses []*Response) time.Duration {
-	size := 0.1 * float64(len(responses))
-	minHeap := heap.NewMinHeap(int(size))
-	j := 0
-	for j < int(size) {
-		minHeap.Insert(int(responses[j].Delay))
-		j++
+// Returns the maximum delay of the successful responses in the window
+func (i *Info) max() time.Duration {
+	if i.slices != nil {
+		return i.slicesMax()
 	}
-	if minHeap.Size > 0 {
-		for j < len(responses) && minHeap.Size > 0 {
-			if int(responses[j].Delay) > minHeap.Peek() {
-				minHeap.Remove()
-				minHeap.Insert(int(responses[j].Delay))
-			}
-			j++
+	if len(i.MaxResponsesList) == 0 {
+		return 0
+	}
+	return i.MaxResponsesList[0]
+}
+
+// Returns the configured percentiles of the delays in the window,
+// nil when no response was successful
+func (i *Info) percentiles() []PercentileResult {
+	if i.slices != nil {
+		return i.slicesPercentiles()
+	}
+	return getPercentiles(i.delays(), i.Percentiles)
+}
+
+// Returns the delays of the successful responses in the window.
+// The delays of the failed ones are those of a timeout or of no response at all
+func (i *Info) delays() []time.Duration {
+	delays := make([]time.Duration, 0, i.SuccessfulResponses)
+	for _, r := range i.ResponsesList {
+		if r.Success {
+			delays = append(delays, r.Delay)
 		}
-		return time.Duration(minHeap.Peek())
 	}
-	return -1
+	return delays
 }
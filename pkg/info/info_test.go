@@ -0,0 +1,346 @@
+package info
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+)
+
+// Adds a response every interval, starting at start, with the given outcomes
+func feed(i *Info, start time.Time, interval time.Duration, outcomes ...bool) {
+	for j, ok := range outcomes {
+		i.Update(&Response{
+			Time:    start.Add(time.Duration(j) * interval),
+			Delay:   10 * time.Millisecond,
+			Success: ok,
+		})
+	}
+}
+
+// Test that responses are evicted by their age and not by their number
+func TestInfoEvictsByAge(t *testing.T) {
+	start := time.Now()
+	i := NewInfo(time.Minute, 10*time.Second, false)
+	feed(i, start, 10*time.Second, true, true, true, true, true, true, true, true)
+	// The responses of 0s..60s are within the window of the response at 70s
+	if i.TotalResponses != 7 {
+		t.Errorf("got %d responses, want 7", i.TotalResponses)
+	}
+	// A much later response evicts every previous one
+	i.Update(&Response{Time: start.Add(10 * time.Minute), Success: true})
+	if i.TotalResponses != 1 {
+		t.Errorf("got %d responses after a gap, want 1", i.TotalResponses)
+	}
+}
+
+// Test that availability is calculated over time, counting the gaps as unavailable
+func TestInfoAvailability(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name         string
+		fill         func(i *Info)
+		availability float64
+		coverage     float64
+	}{
+		{
+			name: "all successful",
+			fill: func(i *Info) {
+				feed(i, start, 10*time.Second, true, true, true, true, true)
+			},
+			availability: 100,
+			coverage:     100,
+		},
+		{
+			name: "one failure covers the time since the previous response",
+			fill: func(i *Info) {
+				feed(i, start, 10*time.Second, true, true, true, true, false)
+			},
+			availability: 75,
+			coverage:     100,
+		},
+		{
+			name: "a gap without responses",
+			fill: func(i *Info) {
+				feed(i, start, 10*time.Second, true, true, true)
+				feed(i, start.Add(60*time.Second), 10*time.Second, true, true)
+			},
+			// 20s after the first responses, 10s after the second,
+			// and the 15s of the 40s gap that a late response covers
+			availability: 45.0 * 100 / 70,
+			coverage:     45.0 * 100 / 70,
+		},
+	}
+	for _, tt := range tests {
+		i := NewInfo(time.Hour, 10*time.Second, false)
+		tt.fill(i)
+		res := i.GetResult()
+		if diff := res.Availability - tt.availability; diff > 0.1 || diff < -0.1 {
+			t.Errorf("%s: got availability %v, want %v", tt.name, res.Availability, tt.availability)
+		}
+		if diff := res.Coverage - tt.coverage; diff > 0.1 || diff < -0.1 {
+			t.Errorf("%s: got coverage %v, want %v", tt.name, res.Coverage, tt.coverage)
+		}
+	}
+}
+
+// Test that the oldest response only counts the part of the time it covers within the window
+func TestInfoAvailabilityWindowStart(t *testing.T) {
+	start := time.Now()
+	i := NewInfo(time.Minute, 20*time.Second, false)
+	feed(i, start, 20*time.Second, false, false, false, true, true, true, true)
+	// The window of the response at 120s begins at 60s: the response at 60s
+	// covers no time in it, and the responses at 80s..120s cover 60s
+	res := i.GetResult()
+	if res.Availability != 100 || res.Coverage != 100 {
+		t.Errorf("got availability %v and coverage %v, want 100", res.Availability, res.Coverage)
+	}
+}
+
+// Test that a window stored in sketches gives the same results as one keeping every response,
+// apart from the percentiles that are within the accuracy of the sketch
+func TestInfoSketch(t *testing.T) {
+	start := time.Now()
+	exact := NewInfo(time.Minute, time.Second, false)
+	sketched := NewInfo(time.Minute, time.Second, false)
+	if err := sketched.UseSketch(0.01); err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < 600; j++ {
+		res := Response{
+			Time:    start.Add(time.Duration(j) * time.Second),
+			Delay:   time.Duration(1+j%97) * time.Millisecond,
+			Status:  200,
+			Success: j%10 != 0,
+		}
+		if !res.Success {
+			res.Status = 500
+		}
+		r1, r2 := res, res
+		exact.Update(&r1)
+		sketched.Update(&r2)
+	}
+	want, got := exact.GetResult(), sketched.GetResult()
+	// The sketched window may extend up to a slice further in the past
+	if diff := got.Availability - want.Availability; diff > 1 || diff < -1 {
+		t.Errorf("got availability %v, want %v", got.Availability, want.Availability)
+	}
+	if got.Max != want.Max {
+		t.Errorf("got max %v, want %v", got.Max, want.Max)
+	}
+	for j, p := range got.Percentiles {
+		w := want.Percentiles[j].Value
+		if diff := float64(p.Value - w); diff > 0.01*float64(w)+float64(time.Millisecond) || -diff > 0.01*float64(w)+float64(time.Millisecond) {
+			t.Errorf("got %s %v, want %v", p.Name(), p.Value, w)
+		}
+	}
+	if sketched.TotalResponses < exact.TotalResponses || sketched.TotalResponses > exact.TotalResponses+1 {
+		t.Errorf("got %d responses, want %d", sketched.TotalResponses, exact.TotalResponses)
+	}
+}
+
+// Test the Apdex score of a window, and the alert firing when it drops
+func TestInfoApdex(t *testing.T) {
+	start := time.Now()
+	i := NewInfo(time.Minute, time.Second, true)
+	i.SetApdex(100*time.Millisecond, 0.7)
+	delays := []time.Duration{50, 100, 150, 400, 500, 90}
+	for j, d := range delays {
+		i.Update(&Response{
+			Time:    start.Add(time.Duration(j) * time.Second),
+			Delay:   d * time.Millisecond,
+			Success: j != len(delays)-1,
+		})
+	}
+	// 2 satisfied, 2 tolerating, a response slower than 4T and a failure
+	a := i.GetResult().Apdex
+	if a == nil || a.Satisfied != 2 || a.Tolerating != 2 || a.Frustrated != 2 || a.Score != 0.5 {
+		t.Fatalf("got %+v, want a score of 0.5", a)
+	}
+	// The availability stays above its threshold, but the fifth response drops the score to 0.6
+	if i.Alert.AlertState != alert.Unavailable || i.Alert.Reason != "apdex 0.60 below 0.70" {
+		t.Errorf("got state %v with reason %q, want the alert to fire", i.Alert.AlertState, i.Alert.Reason)
+	}
+	if NewInfo(time.Minute, time.Second, false).apdex() != nil {
+		t.Errorf("got a score without a threshold")
+	}
+}
+
+// Test the distribution of the response times, as responses enter and leave the window
+func TestInfoDistribution(t *testing.T) {
+	start := time.Now()
+	for _, sketched := range []bool{false, true} {
+		i := NewInfo(time.Minute, time.Second, false)
+		if sketched {
+			i.UseSketch(0.01)
+		}
+		if err := i.SetBuckets([]time.Duration{10 * time.Millisecond, 100 * time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+		// The first response leaves the window of the last one
+		delays := []time.Duration{500, 10, 30, 10, 30, 200}
+		for j, d := range delays {
+			i.Update(&Response{
+				Time:    start.Add(time.Duration(j) * 15 * time.Second),
+				Delay:   d * time.Millisecond,
+				Success: true,
+			})
+		}
+		res := i.GetResult()
+		if res.Min != 10*time.Millisecond {
+			t.Errorf("sketched %v: got min %v, want 10ms", sketched, res.Min)
+		}
+		// The differences 20, 20, 20 and 170ms, but not the 490ms from the evicted response
+		if want := 57500 * time.Microsecond; !sketched && res.Jitter != want {
+			t.Errorf("got jitter %v, want %v", res.Jitter, want)
+		}
+		// The mean is 56ms
+		if want := 72553 * time.Microsecond; !sketched && res.StdDev.Round(time.Millisecond) != want.Round(time.Millisecond) {
+			t.Errorf("got standard deviation %v, want %v", res.StdDev, want)
+		}
+		if got, want := res.Histogram.Counts, []int{2, 4, 5}; !sketched && !equalInts(got, want) {
+			t.Errorf("got cumulative buckets %v, want %v", got, want)
+		}
+		if got, want := res.Histogram.Buckets(), []int{2, 2, 1}; !sketched && !equalInts(got, want) {
+			t.Errorf("got buckets %v, want %v", got, want)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for j := range a {
+		if a[j] != b[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// Test the ratio of responses that did not meet the latency objective, as they leave the window
+func TestInfoErrorRatio(t *testing.T) {
+	for _, sketched := range []bool{false, true} {
+		start := time.Now()
+		i := NewInfo(time.Minute, time.Second, false)
+		if sketched {
+			i.UseSketch(0.01)
+		}
+		i.SetLatencyObjective(100 * time.Millisecond)
+		if _, ok := i.ErrorRatio(); ok {
+			t.Errorf("sketch %v: expected no ratio without responses", sketched)
+		}
+		// A slow and a failed response, followed by a minute of fast ones
+		i.Update(&Response{Time: start, Delay: 200 * time.Millisecond, Success: true})
+		i.Update(&Response{Time: start.Add(time.Second), Delay: 10 * time.Millisecond})
+		for j := 2; j < 4; j++ {
+			i.Update(&Response{Time: start.Add(time.Duration(j) * time.Second), Delay: 10 * time.Millisecond, Success: true})
+		}
+		if got, _ := i.ErrorRatio(); got != 0.5 {
+			t.Errorf("sketch %v: got %v, want 0.5", sketched, got)
+		}
+		i.Update(&Response{Time: start.Add(2 * time.Minute), Delay: 10 * time.Millisecond, Success: true})
+		if got, _ := i.ErrorRatio(); got != 0 {
+			t.Errorf("sketch %v: got %v after the eviction, want 0", sketched, got)
+		}
+	}
+}
+
+// Test that the percentiles leave out the failed responses, which have no meaningful delay
+func TestInfoPercentilesFailures(t *testing.T) {
+	for _, sketched := range []bool{false, true} {
+		start := time.Now()
+		i := NewInfo(time.Minute, time.Second, false)
+		if sketched {
+			i.UseSketch(0.01)
+		}
+		i.Update(&Response{Time: start})
+		if p := i.GetResult().Percentiles; p != nil {
+			t.Errorf("sketch %v: got %v without successful responses, want none", sketched, p)
+		}
+		// Half of the checks time out
+		for j := 1; j <= 10; j++ {
+			res := &Response{Time: start.Add(time.Duration(j) * time.Second)}
+			if j%2 == 0 {
+				res.Delay = 100 * time.Millisecond
+				res.Success = true
+			}
+			i.Update(res)
+		}
+		for _, p := range i.GetResult().Percentiles {
+			if p.Value != 100*time.Millisecond {
+				t.Errorf("sketch %v: got %s %v, want 100ms", sketched, p.Name(), p.Value)
+			}
+		}
+	}
+}
+
+// Test that the distribution of the response times leaves out the failed responses
+func TestInfoDistributionFailures(t *testing.T) {
+	for _, sketched := range []bool{false, true} {
+		start := time.Now()
+		i := NewInfo(time.Minute, time.Second, false)
+		if sketched {
+			i.UseSketch(0.01)
+		}
+		// Every other check times out
+		for j := 0; j < 10; j++ {
+			res := &Response{Time: start.Add(time.Duration(j) * time.Second), Failure: ResponseTimeout}
+			if j%2 == 0 {
+				res = &Response{Time: res.Time, Delay: 100 * time.Millisecond, Success: true}
+			}
+			i.Update(res)
+		}
+		res := i.GetResult()
+		if res.Min != 100*time.Millisecond || res.Max != 100*time.Millisecond {
+			t.Errorf("sketch %v: got min %v and max %v, want 100ms", sketched, res.Min, res.Max)
+		}
+		if res.Average != 100*time.Millisecond {
+			t.Errorf("sketch %v: got average %v, want 100ms", sketched, res.Average)
+		}
+		if res.StdDev != 0 || res.Jitter != 0 {
+			t.Errorf("sketch %v: got standard deviation %v and jitter %v, want 0", sketched, res.StdDev, res.Jitter)
+		}
+		if n := res.Histogram.Counts[len(res.Histogram.Counts)-1]; n != 5 {
+			t.Errorf("sketch %v: got %d responses in the histogram, want 5", sketched, n)
+		}
+		if sketched {
+			continue
+		}
+		// The responses up to 4s leave the window, along with the jitter of the one at 6s
+		i.Update(&Response{Time: start.Add(65 * time.Second), Delay: 300 * time.Millisecond, Success: true})
+		if got := i.GetResult().Jitter; got != 100*time.Millisecond {
+			t.Errorf("got jitter %v after the eviction, want 100ms", got)
+		}
+	}
+}
+
+// Test that a window without limit counts the failures per class only, as their reasons are never removed
+func TestInfoFailureReasons(t *testing.T) {
+	start := time.Now()
+	for _, duration := range []time.Duration{0, time.Minute} {
+		i := NewInfo(duration, time.Second, false)
+		i.UseSketch(0.01)
+		for j := 0; j < 10; j++ {
+			i.Update(&Response{
+				Time:    start.Add(time.Duration(j) * time.Second),
+				Failure: ConnectionReset,
+				Reason:  fmt.Sprintf("read tcp 10.0.0.1:%d->10.0.0.2:443: connection reset by peer", 50000+j),
+			})
+		}
+		res := i.GetResult()
+		if want := map[FailureClass]int{ConnectionReset: 10}; !reflect.DeepEqual(res.FailureClasses, want) {
+			t.Errorf("duration %v: got classes %v, want %v", duration, res.FailureClasses, want)
+		}
+		want := 10
+		if duration == 0 {
+			want = 0
+		}
+		if len(res.Failures) != want || len(i.FailureReasons) != want {
+			t.Errorf("duration %v: got %d reasons, want %d", duration, len(res.Failures), want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package info
+
+import "time"
+
+// Sets the response time that a successful response must not exceed to meet the objective
+// of the window, e.g. the 300ms of "95% of requests under 300ms". When zero, every
+// successful response meets it. It must be called before the first update
+func (i *Info) SetLatencyObjective(threshold time.Duration) {
+	i.latencyObjective = threshold
+}
+
+// Reports whether a response meets the objective of the window
+func (i *Info) meetsObjective(res *Response) bool {
+	return res.Success && (i.latencyObjective <= 0 || res.Delay <= i.latencyObjective)
+}
+
+// Returns the ratio of the responses of the window that did not meet the objective,
+// and false when the window has no responses
+func (i *Info) ErrorRatio() (float64, bool) {
+	if i.TotalResponses == 0 {
+		return 0, false
+	}
+	return float64(i.TotalResponses-i.good) / float64(i.TotalResponses), true
+}
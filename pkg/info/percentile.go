@@ -0,0 +1,60 @@
+package info
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The percentiles calculated, when none are configured for a window
+var DefaultPercentiles = []float64{50, 90, 95, 99}
+
+// PercentileResult is a single percentile of the response times of a window
+type PercentileResult struct {
+	Percentile float64       `json:"percentile"`
+	Value      time.Duration `json:"value"`
+}
+
+// Formats the percentile as e.g. "p99.9"
+func (p PercentileResult) Name() string {
+	return "p" + strconv.FormatFloat(p.Percentile, 'f', -1, 64)
+}
+
+// Checks that a percentile lies in (0, 100]
+func ValidatePercentile(p float64) error {
+	if math.IsNaN(p) || p <= 0 || p > 100 {
+		return fmt.Errorf("invalid percentile %v, must be greater than 0 and at most 100", p)
+	}
+	return nil
+}
+
+// Returns the given percentiles of the durations, using the nearest-rank method.
+// Every value is one of the durations, so that any number of samples gives
+// a meaningful result, e.g. the p99 of fewer than 100 samples is their maximum
+func getPercentiles(durations []time.Duration, percentiles []float64) []PercentileResult {
+	if len(durations) == 0 {
+		return nil
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+	res := make([]PercentileResult, len(percentiles))
+	for j, p := range percentiles {
+		res[j] = PercentileResult{Percentile: p, Value: nearestRank(sorted, p)}
+	}
+	return res
+}
+
+// Returns the percentile p of the sorted durations
+func nearestRank(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,38 @@
+package info
+
+import (
+	"testing"
+	"time"
+)
+
+// Test the nearest-rank percentiles, including windows with few samples
+func TestGetPercentiles(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		res := make([]time.Duration, len(values))
+		for i, v := range values {
+			res[i] = time.Duration(v) * time.Millisecond
+		}
+		return res
+	}
+	tests := []struct {
+		durations   []time.Duration
+		percentiles []float64
+		want        []time.Duration
+	}{
+		{ms(7), []float64{50, 99}, ms(7, 7)},
+		{ms(3, 1, 2), []float64{50, 90, 100}, ms(2, 3, 3)},
+		{ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), []float64{10, 50, 95, 99.9}, ms(1, 5, 10, 10)},
+		{ms(10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 20, 30), []float64{90}, ms(20)},
+	}
+	for i, tt := range tests {
+		got := getPercentiles(tt.durations, tt.percentiles)
+		for j, p := range got {
+			if p.Percentile != tt.percentiles[j] || p.Value != tt.want[j] {
+				t.Errorf("test %d: got %s %v, want p%v %v", i, p.Name(), p.Value, tt.percentiles[j], tt.want[j])
+			}
+		}
+	}
+	if got := getPercentiles(nil, DefaultPercentiles); got != nil {
+		t.Errorf("got %v without samples, want nil", got)
+	}
+}
@@ -0,0 +1,252 @@
+package info
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/sketch"
+)
+
+// Resolution is the width of the buckets of a rollup level, and how long they are kept
+type Resolution struct {
+	Width     time.Duration
+	Retention time.Duration
+}
+
+// The levels of a rollup, when none are given: a day of minutes,
+// a month of hours and more than a year of days
+var DefaultResolutions = []Resolution{
+	{Width: time.Minute, Retention: 24 * time.Hour},
+	{Width: time.Hour, Retention: 31 * 24 * time.Hour},
+	{Width: 24 * time.Hour, Retention: 400 * 24 * time.Hour},
+}
+
+// Bucket summarizes the responses of a period of time
+type Bucket struct {
+	Start time.Time
+	Width time.Duration
+	// The number of responses, and of the successful ones
+	Count     int
+	Successes int
+	// The sum and the maximum of the response times of the successful responses,
+	// as the delay of a failed one is that of a timeout or of no response at all
+	Sum    time.Duration
+	Max    time.Duration
+	delays *sketch.Sketch
+}
+
+// Returns the ratio of successful responses, between 0 and 1
+func (b *Bucket) Availability() float64 {
+	if b.Count == 0 {
+		return 0
+	}
+	return float64(b.Successes) / float64(b.Count)
+}
+
+// Returns the average response time of the successful responses
+func (b *Bucket) Average() time.Duration {
+	if b.Successes == 0 {
+		return 0
+	}
+	return b.Sum / time.Duration(b.Successes)
+}
+
+// Returns the p percentile (0 < p <= 100) of the response times of the successful
+// responses, within the accuracy of the sketch of the rollup
+func (b *Bucket) Percentile(p float64) time.Duration {
+	return time.Duration(b.delays.Quantile(p / 100))
+}
+
+func (b *Bucket) add(res *Response) {
+	b.Count++
+	if !res.Success {
+		return
+	}
+	b.Successes++
+	b.Sum += res.Delay
+	if res.Delay > b.Max {
+		b.Max = res.Delay
+	}
+	b.delays.Add(float64(res.Delay))
+}
+
+func (b *Bucket) merge(o *Bucket) {
+	b.Count += o.Count
+	b.Successes += o.Successes
+	b.Sum += o.Sum
+	if o.Max > b.Max {
+		b.Max = o.Max
+	}
+	// Every sketch of a rollup has the same accuracy
+	b.delays.Merge(o.delays)
+}
+
+// Rollup aggregates the responses into buckets of increasing width, e.g. minutes,
+// hours and days, so that the history of a website can be queried without keeping
+// the responses. The responses are counted in the buckets of the first level,
+// and every bucket is rolled into the next level once the following one starts.
+// Buckets are aligned to the zero time, so that days start at midnight UTC
+type Rollup struct {
+	levels   []*rollupLevel
+	accuracy float64
+}
+
+type rollupLevel struct {
+	Resolution
+	// In increasing order of start, the last one may still be updated
+	buckets []*Bucket
+}
+
+// Creates a rollup of the given levels, whose quantile sketches have the given accuracy.
+// The width of every level must be a multiple of the width of the previous one
+func NewRollup(resolutions []Resolution, accuracy float64) (*Rollup, error) {
+	if len(resolutions) == 0 {
+		return nil, fmt.Errorf("no resolutions defined")
+	}
+	if _, err := sketch.New(accuracy, sketch.DefaultMaxBins); err != nil {
+		return nil, err
+	}
+	r := &Rollup{accuracy: accuracy}
+	for j, res := range resolutions {
+		if res.Width <= 0 {
+			return nil, fmt.Errorf("invalid width %v, must be positive", res.Width)
+		}
+		if j > 0 && res.Width%resolutions[j-1].Width != 0 {
+			return nil, fmt.Errorf("invalid width %v, must be a multiple of %v", res.Width, resolutions[j-1].Width)
+		}
+		if res.Retention < res.Width {
+			return nil, fmt.Errorf("invalid retention %v, must be at least the width %v", res.Retention, res.Width)
+		}
+		r.levels = append(r.levels, &rollupLevel{Resolution: res})
+	}
+	return r, nil
+}
+
+// Returns the widths of the buckets of every level
+func (r *Rollup) Widths() []time.Duration {
+	widths := make([]time.Duration, len(r.levels))
+	for j, l := range r.levels {
+		widths[j] = l.Width
+	}
+	return widths
+}
+
+func (r *Rollup) newBucket(start time.Time, width time.Duration) *Bucket {
+	// The accuracy has been validated by NewRollup
+	s, _ := sketch.New(r.accuracy, sketch.DefaultMaxBins)
+	return &Bucket{Start: start, Width: width, delays: s}
+}
+
+// Adds a response to the bucket of the first level it belongs to.
+// Responses older than the most recent bucket are ignored, as it has been rolled up already
+func (r *Rollup) Add(res *Response) {
+	l := r.levels[0]
+	start := res.Time.Truncate(l.Width)
+	if n := len(l.buckets); n > 0 {
+		last := l.buckets[n-1]
+		if start.Before(last.Start) {
+			return
+		}
+		if last.Start.Equal(start) {
+			last.add(res)
+			return
+		}
+	}
+	b := r.newBucket(start, l.Width)
+	b.add(res)
+	r.push(0, b)
+}
+
+// Appends a new bucket to a level, rolling the previous one into the next level
+func (r *Rollup) push(level int, b *Bucket) {
+	l := r.levels[level]
+	if n := len(l.buckets); n > 0 {
+		r.roll(level+1, l.buckets[n-1])
+	}
+	l.buckets = append(l.buckets, b)
+	// Drop the buckets that are out of retention, they have been rolled already
+	cutoff := b.Start.Add(-l.Retention)
+	drop := 0
+	for drop < len(l.buckets) && !l.buckets[drop].Start.After(cutoff) {
+		drop++
+	}
+	l.buckets = l.buckets[drop:]
+}
+
+// Merges a completed bucket into the bucket of the given level it belongs to
+func (r *Rollup) roll(level int, b *Bucket) {
+	if level >= len(r.levels) {
+		return
+	}
+	l := r.levels[level]
+	start := b.Start.Truncate(l.Width)
+	if n := len(l.buckets); n > 0 && l.buckets[n-1].Start.Equal(start) {
+		l.buckets[n-1].merge(b)
+		return
+	}
+	next := r.newBucket(start, l.Width)
+	next.merge(b)
+	r.push(level, next)
+}
+
+// Returns the buckets of the given width that overlap the period from..to, in order of time.
+// Periods without responses have no bucket. The buckets are copies that include
+// the responses of the finer levels that have not been rolled up yet
+func (r *Rollup) Query(width time.Duration, from, to time.Time) ([]*Bucket, error) {
+	level := -1
+	for j, l := range r.levels {
+		if l.Width == width {
+			level = j
+		}
+	}
+	if level < 0 {
+		return nil, fmt.Errorf("no buckets of width %v", width)
+	}
+	res := make([]*Bucket, 0)
+	for _, b := range r.levels[level].buckets {
+		if b.Start.Add(width).After(from) && b.Start.Before(to) {
+			c := r.newBucket(b.Start, width)
+			c.merge(b)
+			res = append(res, c)
+		}
+	}
+	// The last bucket of every finer level is the only one not rolled into the next level yet
+	for j := level - 1; j >= 0; j-- {
+		l := r.levels[j]
+		if len(l.buckets) == 0 {
+			continue
+		}
+		b := l.buckets[len(l.buckets)-1]
+		start := b.Start.Truncate(width)
+		if !start.Add(width).After(from) || !start.Before(to) {
+			continue
+		}
+		if n := len(res); n > 0 && res[n-1].Start.Equal(start) {
+			res[n-1].merge(b)
+			continue
+		}
+		c := r.newBucket(start, width)
+		c.merge(b)
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+// Returns a single bucket summarizing the period from..to, merged from the buckets of the
+// finest level that keeps the whole period. Buckets that partly overlap the period are included
+func (r *Rollup) Summary(from, to time.Time) *Bucket {
+	level := r.levels[len(r.levels)-1]
+	for _, l := range r.levels {
+		if l.Retention >= to.Sub(from) {
+			level = l
+			break
+		}
+	}
+	res := r.newBucket(from, to.Sub(from))
+	// The width is one of the levels
+	buckets, _ := r.Query(level.Width, from, to)
+	for _, b := range buckets {
+		res.merge(b)
+	}
+	return res
+}
@@ -0,0 +1,131 @@
+package info
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Test the daily availability and p95 of a month of responses, answered from the rollup alone
+func TestRollupDays(t *testing.T) {
+	r, err := NewRollup(DefaultResolutions, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	const days, perDay = 30, 144
+	for d := 0; d < days; d++ {
+		for j := 0; j < perDay; j++ {
+			r.Add(&Response{
+				Time: start.Add(time.Duration(d)*24*time.Hour + time.Duration(j)*10*time.Minute),
+				// Every day a bit slower, from 1ms to 144ms within the day
+				Delay: time.Duration(d+j+1) * time.Millisecond,
+				// The first d responses of day d fail
+				Success: j >= d,
+			})
+		}
+	}
+	buckets, err := r.Query(24*time.Hour, start, start.Add(days*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != days {
+		t.Fatalf("got %d days, want %d", len(buckets), days)
+	}
+	for d, b := range buckets {
+		if want := start.Add(time.Duration(d) * 24 * time.Hour); !b.Start.Equal(want) {
+			t.Errorf("day %d: got start %v, want %v", d, b.Start, want)
+		}
+		if b.Count != perDay {
+			t.Errorf("day %d: got %d responses, want %d", d, b.Count, perDay)
+		}
+		if want := float64(perDay-d) / perDay; b.Availability() != want {
+			t.Errorf("day %d: got availability %v, want %v", d, b.Availability(), want)
+		}
+		// The response times of the successful responses are 2d+1ms to d+144ms,
+		// e.g. the nearest rank of the 95th percentile of 144 responses is the 137th
+		rank := int(math.Ceil(0.95 * float64(perDay-d)))
+		want := float64(2*d+rank) * float64(time.Millisecond)
+		if got := float64(b.Percentile(95)); got < want*0.99 || got > want*1.01 {
+			t.Errorf("day %d: got p95 %v, want %v", d, b.Percentile(95), time.Duration(want))
+		}
+		if want := time.Duration(d+perDay) * time.Millisecond; b.Max != want {
+			t.Errorf("day %d: got max %v, want %v", d, b.Max, want)
+		}
+		if want := time.Duration(3*d+145) * time.Millisecond / 2; b.Average() != want {
+			t.Errorf("day %d: got average %v, want %v", d, b.Average(), want)
+		}
+	}
+	// Only the last day of minutes is kept
+	minutes, _ := r.Query(time.Minute, start, start.Add(days*24*time.Hour))
+	if len(minutes) != perDay {
+		t.Errorf("got %d minutes, want %d", len(minutes), perDay)
+	}
+	if _, err := r.Query(time.Second, start, start.Add(time.Hour)); err == nil {
+		t.Errorf("expected an error for a width without a level")
+	}
+	// The last day is summarized from its minutes, the whole month from the days
+	if day := r.Summary(start.Add((days-1)*24*time.Hour), start.Add(days*24*time.Hour)); day.Count != perDay || day.Successes != perDay-days+1 {
+		t.Errorf("got %d responses and %d successes in the last day, want %d and %d", day.Count, day.Successes, perDay, perDay-days+1)
+	}
+	if month := r.Summary(start, start.Add(days*24*time.Hour)); month.Count != days*perDay || month.Max != time.Duration(days-1+perDay)*time.Millisecond {
+		t.Errorf("got %d responses and max %v in the month, want %d and %v", month.Count, month.Max, days*perDay, time.Duration(days-1+perDay)*time.Millisecond)
+	}
+}
+
+// Test that the responses that are not rolled up yet are included in the coarser buckets
+func TestRollupPending(t *testing.T) {
+	r, err := NewRollup(DefaultResolutions, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 23, 58, 0, 0, time.UTC)
+	for j := 0; j < 5; j++ {
+		r.Add(&Response{Time: start.Add(time.Duration(j) * time.Minute), Delay: time.Millisecond, Success: true})
+	}
+	// A response of a past minute cannot be rolled up anymore
+	r.Add(&Response{Time: start, Delay: time.Millisecond, Success: true})
+	tests := []struct {
+		width  time.Duration
+		counts []int
+	}{
+		{time.Minute, []int{1, 1, 1, 1, 1}},
+		{time.Hour, []int{2, 3}},
+		{24 * time.Hour, []int{2, 3}},
+	}
+	for _, tt := range tests {
+		buckets, err := r.Query(tt.width, start.Add(-24*time.Hour), start.Add(24*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := make([]int, len(buckets))
+		for j, b := range buckets {
+			counts[j] = b.Count
+		}
+		if !equalInts(counts, tt.counts) {
+			t.Errorf("%v: got %v, want %v", tt.width, counts, tt.counts)
+		}
+	}
+	// Querying does not change the rollup
+	if buckets, _ := r.Query(24*time.Hour, start, start.Add(time.Hour)); buckets[1].Count != 3 {
+		t.Errorf("got %d responses after querying again, want 3", buckets[1].Count)
+	}
+}
+
+func TestNewRollup(t *testing.T) {
+	tests := []struct {
+		name        string
+		resolutions []Resolution
+		accuracy    float64
+	}{
+		{"no levels", nil, 0.01},
+		{"invalid accuracy", DefaultResolutions, 0},
+		{"not a multiple", []Resolution{{time.Minute, time.Hour}, {90 * time.Second, time.Hour}}, 0.01},
+		{"retention shorter than width", []Resolution{{time.Hour, time.Minute}}, 0.01},
+	}
+	for _, tt := range tests {
+		if _, err := NewRollup(tt.resolutions, tt.accuracy); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
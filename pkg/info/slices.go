@@ -0,0 +1,264 @@
+package info
+
+import (
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/sketch"
+)
+
+// The number of slices a window backed by sketches is divided into.
+// Responses are evicted a whole slice at a time, so the window may extend
+// up to one slice further in the past
+const sketchSlices = 60
+
+// slice summarizes the responses of a part of a window backed by sketches
+type slice struct {
+	start, end time.Time
+	// The earliest time covered by the responses of the slice
+	from time.Time
+	// The delays of the successful responses
+	delays     *sketch.Sketch
+	total      int
+	successful int
+	// The sum, the maximum and the minimum of the delays of the successful responses
+	sum         time.Duration
+	max, min    time.Duration
+	statusCodes map[int]int
+	reasons     map[string]int
+	classes     map[FailureClass]int
+	// The time covered by all the responses, and by the successful ones
+	covered, available time.Duration
+	// The number of satisfied and tolerating responses
+	satisfied, tolerating int
+	// The number of responses that met the objective
+	good int
+	// The distribution of the response times, as in Info
+	buckets               []int
+	sumDelays, sumSquares float64
+	jitterSum             time.Duration
+	jitterCount           int
+	regression            regression
+	// DNS, connect, TLS, server, transfer and total, as in Timing
+	phases [6]*phase
+}
+
+// phase summarizes one phase of the requests of a slice
+type phase struct {
+	count    int
+	sum, max time.Duration
+	sketch   *sketch.Sketch
+}
+
+// Stores the window in slices of quantile sketches instead of keeping every response,
+// so that the memory used does not depend on the number of responses.
+// The percentiles are calculated within the given relative accuracy, e.g. 0.01.
+// It must be called before the first update
+func (i *Info) UseSketch(accuracy float64) error {
+	if _, err := sketch.New(accuracy, sketch.DefaultMaxBins); err != nil {
+		return err
+	}
+	i.accuracy = accuracy
+	i.slices = make([]*slice, 0, sketchSlices+1)
+	return nil
+}
+
+// Reports whether the window is stored in sketches
+func (i *Info) Sketched() bool {
+	return i.slices != nil
+}
+
+func (i *Info) newSketch() *sketch.Sketch {
+	// The accuracy has been validated by UseSketch
+	s, _ := sketch.New(i.accuracy, sketch.DefaultMaxBins)
+	return s
+}
+
+// Returns the slice a response of the given time belongs to, creating it if needed
+func (i *Info) currentSlice(t time.Time) *slice {
+	width := i.Duration / sketchSlices
+	if n := len(i.slices); n > 0 && (width == 0 || t.Before(i.slices[n-1].end)) {
+		return i.slices[n-1]
+	}
+	s := &slice{
+		start:       t,
+		from:        t,
+		delays:      i.newSketch(),
+		statusCodes: make(map[int]int),
+		reasons:     make(map[string]int),
+		classes:     make(map[FailureClass]int),
+		buckets:     make([]int, len(i.bucketCounts)),
+	}
+	if width > 0 {
+		s.start = t.Truncate(width)
+		s.end = s.start.Add(width)
+		s.from = s.start
+	}
+	i.slices = append(i.slices, s)
+	return s
+}
+
+// Adds a response, and what is derived from it, to the slice
+func (i *Info) addToSlice(res *Response, e entry) {
+	s := i.currentSlice(res.Time)
+	if from := res.Time.Add(-e.covered); from.Before(s.from) {
+		s.from = from
+	}
+	s.total++
+	if res.Status != 0 {
+		s.statusCodes[res.Status]++
+	}
+	if res.Success {
+		// The response times, as in countDistribution
+		s.delays.Add(float64(res.Delay))
+		if s.successful == 0 || res.Delay < s.min {
+			s.min = res.Delay
+		}
+		if res.Delay > s.max {
+			s.max = res.Delay
+		}
+		s.buckets[i.bucket(res.Delay)]++
+		s.sumDelays += float64(res.Delay)
+		s.sumSquares += float64(res.Delay) * float64(res.Delay)
+		if e.paired {
+			s.jitterSum += e.jitter
+			s.jitterCount++
+		}
+	}
+	s.covered += e.covered
+	if e.good {
+		s.good++
+	}
+	switch e.apdex {
+	case satisfied:
+		s.satisfied++
+	case tolerating:
+		s.tolerating++
+	}
+	if res.Success {
+		s.successful++
+		s.sum += res.Delay
+		s.available += e.covered
+		s.regression.count(i.since, res, 1)
+	} else {
+		if i.countsReasons() {
+			s.reasons[res.Reason]++
+		}
+		s.classes[res.Failure]++
+	}
+	if t := res.Timing; t != nil {
+		for j, d := range []time.Duration{t.DNS, t.Connect, t.TLS, t.Server, t.Transfer, t.Total} {
+			if s.phases[j] == nil {
+				s.phases[j] = &phase{sketch: i.newSketch()}
+			}
+			p := s.phases[j]
+			p.count++
+			p.sum += d
+			if d > p.max {
+				p.max = d
+			}
+			p.sketch.Add(float64(d))
+		}
+	}
+}
+
+// Removes the oldest slice from the time window
+func (i *Info) evictSlice() {
+	s := i.slices[0]
+	i.slices = i.slices[1:]
+	i.TotalResponses -= s.total
+	i.SuccessfulResponses -= s.successful
+	i.SumResponses -= s.sum
+	i.coveredTime -= s.covered
+	i.availableTime -= s.available
+	i.satisfied -= s.satisfied
+	i.tolerating -= s.tolerating
+	i.good -= s.good
+	for j, count := range s.buckets {
+		i.bucketCounts[j] -= count
+	}
+	i.sumDelays -= s.sumDelays
+	i.sumSquares -= s.sumSquares
+	i.jitterSum -= s.jitterSum
+	i.jitterCount -= s.jitterCount
+	i.regression.subtract(s.regression)
+	for code, count := range s.statusCodes {
+		if i.StatusCodesCount[code] -= count; i.StatusCodesCount[code] == 0 {
+			delete(i.StatusCodesCount, code)
+		}
+	}
+	for reason, count := range s.reasons {
+		if i.FailureReasons[reason] -= count; i.FailureReasons[reason] == 0 {
+			delete(i.FailureReasons, reason)
+		}
+	}
+	for class, count := range s.classes {
+		if i.FailureClassesCount[class] -= count; i.FailureClassesCount[class] == 0 {
+			delete(i.FailureClassesCount, class)
+		}
+	}
+}
+
+// Returns the maximum delay of the slices
+func (i *Info) slicesMax() time.Duration {
+	var max time.Duration
+	for _, s := range i.slices {
+		if s.max > max {
+			max = s.max
+		}
+	}
+	return max
+}
+
+// Merges the delays of every slice, to calculate the percentiles of the window
+func (i *Info) slicesPercentiles() []PercentileResult {
+	merged := i.newSketch()
+	for _, s := range i.slices {
+		merged.Merge(s.delays)
+	}
+	if merged.Count() == 0 {
+		return nil
+	}
+	res := make([]PercentileResult, len(i.Percentiles))
+	for j, p := range i.Percentiles {
+		res[j] = PercentileResult{Percentile: p, Value: time.Duration(merged.Quantile(p / 100))}
+	}
+	return res
+}
+
+// Merges the phases of every slice, as getTimingResult does for the responses
+func (i *Info) slicesTimingResult() *TimingResult {
+	var merged [6]PhaseResult
+	for j := range merged {
+		var count int
+		var sum, max time.Duration
+		sk := i.newSketch()
+		for _, s := range i.slices {
+			p := s.phases[j]
+			if p == nil {
+				continue
+			}
+			count += p.count
+			sum += p.sum
+			if p.max > max {
+				max = p.max
+			}
+			sk.Merge(p.sketch)
+		}
+		if count == 0 {
+			return nil
+		}
+		merged[j] = PhaseResult{
+			Max:        max.Round(time.Microsecond),
+			Average:    (sum / time.Duration(count)).Round(time.Microsecond),
+			Percentile: time.Duration(sk.Quantile(0.9)).Round(time.Microsecond),
+		}
+	}
+	return &TimingResult{
+		DNS:      merged[0],
+		Connect:  merged[1],
+		TLS:      merged[2],
+		Server:   merged[3],
+		Transfer: merged[4],
+		Total:    merged[5],
+	}
+}
@@ -0,0 +1,89 @@
+package info
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StatusClass groups the status codes by their first digit
+type StatusClass string
+
+const (
+	Status1xx StatusClass = "1xx"
+	Status2xx StatusClass = "2xx"
+	Status3xx StatusClass = "3xx"
+	Status4xx StatusClass = "4xx"
+	Status5xx StatusClass = "5xx"
+	// No response was received, e.g. a timeout or a refused connection
+	NetworkError StatusClass = "network_error"
+)
+
+// The classes in the order they are reported
+var statusClasses = []StatusClass{Status1xx, Status2xx, Status3xx, Status4xx, Status5xx, NetworkError}
+
+// Returns the class of a status code, 0 being a network error
+func ClassOf(code int) StatusClass {
+	if code < 100 || code > 599 {
+		return NetworkError
+	}
+	return StatusClass(fmt.Sprintf("%dxx", code/100))
+}
+
+// StatusCount is the number of responses of a status code
+type StatusCount struct {
+	Code  int `json:"code"`
+	Count int `json:"count"`
+}
+
+// ClassCount is the number of responses of a status class
+type ClassCount struct {
+	Class StatusClass `json:"class"`
+	Count int         `json:"count"`
+}
+
+// StatusResult breaks down the responses of a window by status code
+type StatusResult struct {
+	// The responses per status code, in increasing order of code
+	Codes []StatusCount `json:"codes"`
+	// The responses per class, in the order 1xx to 5xx followed by network errors,
+	// leaving out the classes without responses
+	Classes []ClassCount `json:"classes"`
+	// The ratio of failed responses, between 0 and 1
+	ErrorRate float64 `json:"errorRate"`
+}
+
+// Returns the number of responses of a class
+func (s *StatusResult) Class(class StatusClass) int {
+	for _, c := range s.Classes {
+		if c.Class == class {
+			return c.Count
+		}
+	}
+	return 0
+}
+
+// Returns the breakdown of the responses of the window by status code
+func (i *Info) statusResult() *StatusResult {
+	res := &StatusResult{
+		Codes: make([]StatusCount, 0, len(i.StatusCodesCount)),
+	}
+	perClass := make(map[StatusClass]int)
+	received := 0
+	for code, count := range i.StatusCodesCount {
+		res.Codes = append(res.Codes, StatusCount{Code: code, Count: count})
+		perClass[ClassOf(code)] += count
+		received += count
+	}
+	sort.Slice(res.Codes, func(a, b int) bool { return res.Codes[a].Code < res.Codes[b].Code })
+	// The responses without a status code are not counted in StatusCodesCount
+	perClass[NetworkError] += i.TotalResponses - received
+	for _, class := range statusClasses {
+		if perClass[class] > 0 {
+			res.Classes = append(res.Classes, ClassCount{Class: class, Count: perClass[class]})
+		}
+	}
+	if i.TotalResponses > 0 {
+		res.ErrorRate = float64(i.TotalResponses-i.SuccessfulResponses) / float64(i.TotalResponses)
+	}
+	return res
+}
@@ -0,0 +1,61 @@
+package info
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test the breakdown of the responses by status code and by class
+func TestStatusResult(t *testing.T) {
+	for _, sketched := range []bool{false, true} {
+		start := time.Now()
+		i := NewInfo(time.Hour, time.Second, false)
+		if sketched {
+			if err := i.UseSketch(0.01); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for j, code := range []int{503, 200, 0, 200, 404, 301, 200, 0} {
+			i.Update(&Response{
+				Time:    start.Add(time.Duration(j) * time.Second),
+				Delay:   10 * time.Millisecond,
+				Status:  code,
+				Success: code == 200 || code == 301,
+			})
+		}
+		got := i.GetResult().StatusCodes
+		want := &StatusResult{
+			Codes: []StatusCount{{200, 3}, {301, 1}, {404, 1}, {503, 1}},
+			Classes: []ClassCount{
+				{Status2xx, 3}, {Status3xx, 1}, {Status4xx, 1}, {Status5xx, 1}, {NetworkError, 2},
+			},
+			ErrorRate: 0.5,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sketch %v: got %+v, want %+v", sketched, got, want)
+		}
+		if n := got.Class(NetworkError); n != 2 {
+			t.Errorf("sketch %v: got %d network errors, want 2", sketched, n)
+		}
+	}
+}
+
+func TestClassOf(t *testing.T) {
+	tests := []struct {
+		code int
+		want StatusClass
+	}{
+		{0, NetworkError},
+		{101, Status1xx},
+		{204, Status2xx},
+		{308, Status3xx},
+		{429, Status4xx},
+		{599, Status5xx},
+	}
+	for _, tt := range tests {
+		if got := ClassOf(tt.code); got != tt.want {
+			t.Errorf("%d: got %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package info
+
+import "time"
+
+// Timing is the breakdown of a request into its phases.
+// Phases that did not take place (e.g. DNS lookup on a reused connection) are zero
+type Timing struct {
+	DNS      time.Duration `json:"dns"`
+	Connect  time.Duration `json:"connect"`
+	TLS      time.Duration `json:"tls"`
+	Server   time.Duration `json:"server"`
+	Transfer time.Duration `json:"transfer"`
+	Total    time.Duration `json:"total"`
+	// Whether an idle connection was reused for the request
+	Reused bool `json:"reused"`
+}
+
+// PhaseResult summarizes a single phase over a time window
+type PhaseResult struct {
+	Max        time.Duration `json:"max"`
+	Average    time.Duration `json:"average"`
+	Percentile time.Duration `json:"percentile"`
+}
+
+// TimingResult summarizes every phase over a time window
+type TimingResult struct {
+	DNS      PhaseResult `json:"dns"`
+	Connect  PhaseResult `json:"connect"`
+	TLS      PhaseResult `json:"tls"`
+	Server   PhaseResult `json:"server"`
+	Transfer PhaseResult `json:"transfer"`
+	Total    PhaseResult `json:"total"`
+}
+
+// Calculates the max, average and 90th percentile of each phase,
+// considering only the responses that were received
+func getTimingResult(responses []*Response) *TimingResult {
+	phases := make([][]time.Duration, 6)
+	for _, r := range responses {
+		if r.Timing == nil {
+			continue
+		}
+		phases[0] = append(phases[0], r.Timing.DNS)
+		phases[1] = append(phases[1], r.Timing.Connect)
+		phases[2] = append(phases[2], r.Timing.TLS)
+		phases[3] = append(phases[3], r.Timing.Server)
+		phases[4] = append(phases[4], r.Timing.Transfer)
+		phases[5] = append(phases[5], r.Timing.Total)
+	}
+	if len(phases[5]) == 0 {
+		return nil
+	}
+	return &TimingResult{
+		DNS:      getPhaseResult(phases[0]),
+		Connect:  getPhaseResult(phases[1]),
+		TLS:      getPhaseResult(phases[2]),
+		Server:   getPhaseResult(phases[3]),
+		Transfer: getPhaseResult(phases[4]),
+		Total:    getPhaseResult(phases[5]),
+	}
+}
+
+func getPhaseResult(durations []time.Duration) PhaseResult {
+	var max, sum time.Duration
+	for _, d := range durations {
+		sum += d
+		if d > max {
+			max = d
+		}
+	}
+	return PhaseResult{
+		Max:        max.Round(time.Microsecond),
+		Average:    (sum / time.Duration(len(durations))).Round(time.Microsecond),
+		Percentile: getPercentiles(durations, []float64{90})[0].Value.Round(time.Microsecond),
+	}
+}
+
+// Redirect is a single hop in a chain of redirects
+type Redirect struct {
+	Url    string `json:"url"`
+	Status int    `json:"status"`
+	// Total duration of the request of this hop
+	Duration time.Duration `json:"duration"`
+}
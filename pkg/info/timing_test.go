@@ -0,0 +1,72 @@
+package info
+
+import (
+	"testing"
+	"time"
+)
+
+// Returns a response whose phases all take d, except the total that takes 6d
+func timedResponse(at time.Time, d time.Duration) *Response {
+	return &Response{
+		Time:    at,
+		Delay:   6 * d,
+		Success: true,
+		Timing:  &Timing{DNS: d, Connect: d, TLS: d, Server: d, Transfer: d, Total: 6 * d},
+	}
+}
+
+func TestPhaseResult(t *testing.T) {
+	var durations []time.Duration
+	for j := 10; j >= 1; j-- {
+		durations = append(durations, time.Duration(j)*time.Millisecond)
+	}
+	// The nearest rank of the 90th percentile of 10 durations is the 9th
+	expected := PhaseResult{Max: 10 * time.Millisecond, Average: 5500 * time.Microsecond, Percentile: 9 * time.Millisecond}
+	if got := getPhaseResult(durations); got != expected {
+		t.Errorf("got %+v, expected %+v", got, expected)
+	}
+}
+
+// Test that every phase is aggregated over the responses that were received,
+// both when the window keeps the responses and when it is stored in sketches
+func TestTimingResult(t *testing.T) {
+	start := time.Now()
+	for _, sketch := range []bool{false, true} {
+		i := NewInfo(time.Hour, time.Second, false)
+		if sketch {
+			i.UseSketch(0.01)
+		}
+		for j := 1; j <= 10; j++ {
+			i.Update(timedResponse(start.Add(time.Duration(j)*time.Second), time.Duration(j)*time.Millisecond))
+		}
+		// Failed requests without a response have no timing
+		i.Update(&Response{Time: start.Add(11 * time.Second), Failure: ConnectTimeout})
+
+		res := i.GetResult().Timings
+		if res == nil {
+			t.Fatalf("sketch %v: no timings", sketch)
+		}
+		for _, phase := range []struct {
+			name   string
+			result PhaseResult
+			scale  time.Duration
+		}{
+			{"dns", res.DNS, 1}, {"connect", res.Connect, 1}, {"tls", res.TLS, 1},
+			{"server", res.Server, 1}, {"transfer", res.Transfer, 1}, {"total", res.Total, 6},
+		} {
+			r, scale := phase.result, phase.scale
+			if r.Max != 10*time.Millisecond*scale || r.Average != 5500*time.Microsecond*scale {
+				t.Errorf("sketch %v: %s: got max %v and average %v", sketch, phase.name, r.Max, r.Average)
+			}
+			// Within the accuracy of the sketch
+			p90 := 9 * time.Millisecond * scale
+			if r.Percentile < p90*99/100 || r.Percentile > p90*101/100 {
+				t.Errorf("sketch %v: %s: got p90 %v, expected %v", sketch, phase.name, r.Percentile, p90)
+			}
+		}
+	}
+
+	if res := getTimingResult([]*Response{{Failure: DNSTimeout}}); res != nil {
+		t.Errorf("got %+v, expected no timings without a response", res)
+	}
+}
@@ -0,0 +1,107 @@
+package info
+
+import (
+	"math"
+	"time"
+)
+
+// Direction tells whether the response times of a window are faster or slower than a baseline
+type Direction string
+
+const (
+	// Not enough successful responses in either window
+	TrendUnknown Direction = "unknown"
+	// The change is below the significance threshold
+	TrendStable Direction = "stable"
+	TrendFaster Direction = "faster"
+	TrendSlower Direction = "slower"
+)
+
+// The relative change of the mean response time that is significant, when none is configured
+const DefaultTrendThreshold = 0.1
+
+// The successful responses needed in each window before comparing them
+const MinTrendResponses = 2
+
+// Trend compares the mean response time of a window against a baseline window
+type Trend struct {
+	Mean         time.Duration `json:"mean"`
+	BaselineMean time.Duration `json:"baselineMean"`
+	// The relative change of the mean, e.g. -0.12 when 12% faster than the baseline
+	Change float64 `json:"change"`
+	// The change of the response time per minute within the window, from a linear regression
+	Slope     time.Duration `json:"slope"`
+	Direction Direction     `json:"direction"`
+}
+
+// Compares the successful responses of a window against those of a baseline window.
+// Changes smaller than the threshold (e.g. 0.1 for 10%) are considered stable
+func CompareTrend(window, baseline *Info, threshold float64) Trend {
+	t := Trend{Direction: TrendUnknown}
+	mean, ok := window.Mean()
+	baselineMean, baselineOk := baseline.Mean()
+	if !ok || !baselineOk || window.SuccessfulResponses < MinTrendResponses ||
+		baseline.SuccessfulResponses < MinTrendResponses || baselineMean == 0 {
+		return t
+	}
+	t.Mean, t.BaselineMean = mean, baselineMean
+	t.Change = float64(mean-baselineMean) / float64(baselineMean)
+	t.Slope, _ = window.Slope()
+	switch {
+	case math.Abs(t.Change) < threshold:
+		t.Direction = TrendStable
+	case t.Change < 0:
+		t.Direction = TrendFaster
+	default:
+		t.Direction = TrendSlower
+	}
+	return t
+}
+
+// Returns the mean response time of the successful responses of the window
+func (i *Info) Mean() (time.Duration, bool) {
+	if i.SuccessfulResponses == 0 {
+		return 0, false
+	}
+	return i.SumResponses / time.Duration(i.SuccessfulResponses), true
+}
+
+// Returns the change of the response time per minute, from a linear regression
+// of the successful responses of the window over time
+func (i *Info) Slope() (time.Duration, bool) {
+	n := float64(i.SuccessfulResponses)
+	if n < 2 {
+		return 0, false
+	}
+	r := i.regression
+	sxx := r.t2 - r.t*r.t/n
+	if sxx <= 0 {
+		// Every response at the same time
+		return 0, false
+	}
+	sxy := r.td - r.t*r.d/n
+	return time.Duration(sxy / sxx * 60), true
+}
+
+// regression keeps the sums of a linear regression of the response times over time,
+// with the time in seconds since the first response ever and the response time in nanoseconds
+type regression struct {
+	t, t2, d, td float64
+}
+
+// Adds a successful response to the regression, or removes it when n is -1
+func (r *regression) count(since time.Time, res *Response, n int) {
+	t := res.Time.Sub(since).Seconds()
+	d := float64(res.Delay)
+	r.t += float64(n) * t
+	r.t2 += float64(n) * t * t
+	r.d += float64(n) * d
+	r.td += float64(n) * t * d
+}
+
+func (r *regression) subtract(o regression) {
+	r.t -= o.t
+	r.t2 -= o.t2
+	r.d -= o.d
+	r.td -= o.td
+}
@@ -0,0 +1,85 @@
+package info
+
+import (
+	"testing"
+	"time"
+)
+
+// Creates a window with a response every second, of the given delays in milliseconds.
+// A negative delay is a failed response
+func trendWindow(delays ...int) *Info {
+	start := time.Now()
+	i := NewInfo(time.Hour, time.Second, false)
+	for j, d := range delays {
+		res := &Response{Time: start.Add(time.Duration(j) * time.Second), Success: d >= 0}
+		if d >= 0 {
+			res.Delay = time.Duration(d) * time.Millisecond
+		} else {
+			res.Delay = 10 * time.Second
+		}
+		i.Update(res)
+	}
+	return i
+}
+
+// Test the comparison of a window against a baseline
+func TestCompareTrend(t *testing.T) {
+	tests := []struct {
+		name      string
+		window    *Info
+		baseline  *Info
+		direction Direction
+		change    float64
+	}{
+		{"no responses", trendWindow(), trendWindow(100, 100), TrendUnknown, 0},
+		{"only failures", trendWindow(-1, -1, -1), trendWindow(100, 100), TrendUnknown, 0},
+		{"stable", trendWindow(105, 105), trendWindow(100, 100), TrendStable, 0.05},
+		{"faster", trendWindow(50, 50), trendWindow(100, 100), TrendFaster, -0.5},
+		{"slower", trendWindow(150, 150), trendWindow(100, 100), TrendSlower, 0.5},
+		// Failures count neither in the sum nor in the number of responses
+		{"failures are ignored", trendWindow(100, -1, 100, -1), trendWindow(100, 100), TrendStable, 0},
+	}
+	for _, tt := range tests {
+		got := CompareTrend(tt.window, tt.baseline, DefaultTrendThreshold)
+		if got.Direction != tt.direction {
+			t.Errorf("%s: got %v, want %v", tt.name, got.Direction, tt.direction)
+		}
+		if diff := got.Change - tt.change; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s: got change %v, want %v", tt.name, got.Change, tt.change)
+		}
+	}
+}
+
+// Test the slope of the response times over time
+func TestSlope(t *testing.T) {
+	tests := []struct {
+		name   string
+		window *Info
+		want   time.Duration
+		ok     bool
+	}{
+		{"increasing 1ms per second", trendWindow(10, 11, 12, 13, 14), 60 * time.Millisecond, true},
+		{"decreasing", trendWindow(20, 18, 16), -120 * time.Millisecond, true},
+		{"flat with failures", trendWindow(10, -1, 10, 10), 0, true},
+		{"single response", trendWindow(10), 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.window.Slope()
+		if ok != tt.ok || got.Round(time.Microsecond) != tt.want {
+			t.Errorf("%s: got %v (%v), want %v (%v)", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+	// The slope only considers the responses within the window
+	i := NewInfo(10*time.Second, time.Second, false)
+	start := time.Now()
+	for j := 0; j < 30; j++ {
+		d := 100 * time.Millisecond
+		if j >= 19 {
+			d = time.Duration(j) * time.Millisecond
+		}
+		i.Update(&Response{Time: start.Add(time.Duration(j) * time.Second), Delay: d, Success: true})
+	}
+	if got, _ := i.Slope(); got.Round(time.Microsecond) != 60*time.Millisecond {
+		t.Errorf("got %v after eviction, want 60ms", got)
+	}
+}
@@ -0,0 +1,20 @@
+package monitor
+
+import "time"
+
+// Apdex defines how satisfying the response times of a website are
+type Apdex struct {
+	// Successful responses up to the threshold are satisfying, and up to four times it tolerable.
+	// When zero, no score is calculated
+	Threshold time.Duration
+	// The availability alert also fires when the score of the alert window falls below it, when set
+	AlertBelow float64
+}
+
+// Sets the Apdex threshold of every window
+func (s *Statistics) setApdex(a Apdex) {
+	for _, window := range s.Windows {
+		window.SetApdex(a.Threshold, a.AlertBelow)
+	}
+	s.OverallInfo.SetApdex(a.Threshold, 0)
+}
@@ -0,0 +1,180 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Maximum number of bytes of a response body that are inspected by the assertions
+const MaxBodySize = 1 << 20
+
+// StatusMatcher accepts a status code, either when it belongs
+// in the range [Min, Max] or when it matches the regular expression
+type StatusMatcher struct {
+	Min   int
+	Max   int
+	Regex *regexp.Regexp
+}
+
+// Parses a status code matcher, which can be a single code ("200"),
+// a range ("200-399") or a regular expression ("^2..$")
+func ParseStatusMatcher(s string) (StatusMatcher, error) {
+	s = strings.TrimSpace(s)
+	if code, err := strconv.Atoi(s); err == nil {
+		return StatusMatcher{Min: code, Max: code}, nil
+	}
+	if parts := strings.SplitN(s, "-", 2); len(parts) == 2 {
+		min, errMin := strconv.Atoi(strings.TrimSpace(parts[0]))
+		max, errMax := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if errMin == nil && errMax == nil {
+			if min > max {
+				return StatusMatcher{}, fmt.Errorf("invalid status range %q", s)
+			}
+			return StatusMatcher{Min: min, Max: max}, nil
+		}
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return StatusMatcher{}, fmt.Errorf("invalid status matcher %q: %v", s, err)
+	}
+	return StatusMatcher{Regex: re}, nil
+}
+
+func (s StatusMatcher) Match(status int) bool {
+	if s.Regex != nil {
+		return s.Regex.MatchString(strconv.Itoa(status))
+	}
+	return status >= s.Min && status <= s.Max
+}
+
+// JSONAssertion checks the value found in the path of a json response body.
+// The path consists of object keys and array indices separated by dots, e.g. "data.items.0.status"
+type JSONAssertion struct {
+	Path string
+	// The expected value, compared against strings as is
+	// and against any other value in its json encoding
+	Equals string
+	// When set, only the existence of the path is checked
+	Exists bool
+}
+
+// Assertions are evaluated on every response of a website.
+// A response is successful only when all of them pass
+type Assertions struct {
+	// Accepted status codes, by default 2xx
+	Status          []StatusMatcher
+	BodyContains    []string
+	BodyNotContains []string
+	BodyMatches     []*regexp.Regexp
+	JSON            []JSONAssertion
+}
+
+// Reports whether the response body is required in order to evaluate the assertions
+func (a *Assertions) NeedsBody() bool {
+	if a == nil {
+		return false
+	}
+	return len(a.BodyContains) > 0 || len(a.BodyNotContains) > 0 || len(a.BodyMatches) > 0 || len(a.JSON) > 0
+}
+
+// Checks the status code of a response, and returns the reason it is not accepted
+func (a *Assertions) CheckStatus(status int) string {
+	if a == nil || len(a.Status) == 0 {
+		if status >= 200 && status < 300 {
+			return ""
+		}
+		return fmt.Sprintf("unexpected status %d", status)
+	}
+	for _, s := range a.Status {
+		if s.Match(status) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("unexpected status %d", status)
+}
+
+// Checks the body of a response, and returns the reason of the first failing assertion
+func (a *Assertions) CheckBody(body []byte) string {
+	if a == nil {
+		return ""
+	}
+	for _, s := range a.BodyContains {
+		if !strings.Contains(string(body), s) {
+			return fmt.Sprintf("body does not contain %q", s)
+		}
+	}
+	for _, s := range a.BodyNotContains {
+		if strings.Contains(string(body), s) {
+			return fmt.Sprintf("body contains %q", s)
+		}
+	}
+	for _, re := range a.BodyMatches {
+		if !re.Match(body) {
+			return fmt.Sprintf("body does not match %q", re.String())
+		}
+	}
+	if len(a.JSON) == 0 {
+		return ""
+	}
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Sprintf("body is not valid json: %v", err)
+	}
+	for _, j := range a.JSON {
+		if reason := j.check(doc); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+func (j JSONAssertion) check(doc interface{}) string {
+	val, ok := lookupJSON(doc, j.Path)
+	if !ok {
+		return fmt.Sprintf("json path %q not found", j.Path)
+	}
+	if j.Exists {
+		return ""
+	}
+	var got string
+	if s, isString := val.(string); isString {
+		got = s
+	} else {
+		encoded, _ := json.Marshal(val)
+		got = string(encoded)
+	}
+	if got != j.Equals {
+		return fmt.Sprintf("json path %q is %s, expected %s", j.Path, got, j.Equals)
+	}
+	return ""
+}
+
+// Follows the dot separated path inside a decoded json document
+func lookupJSON(doc interface{}, path string) (interface{}, bool) {
+	cur := doc
+	if path == "" || path == "." {
+		return cur, true
+	}
+	for _, key := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			cur = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"regexp"
+	"testing"
+)
+
+// Test the different forms of status code matchers
+func TestStatusMatcher(t *testing.T) {
+	tests := []struct {
+		matcher string
+		status  int
+		want    bool
+	}{
+		{"200", 200, true},
+		{"200", 201, false},
+		{"200-399", 301, true},
+		{"200-399", 404, false},
+		{"^2..$", 204, true},
+		{"^2..$", 500, false},
+	}
+	for _, tt := range tests {
+		m, err := ParseStatusMatcher(tt.matcher)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.matcher, err)
+		}
+		if got := m.Match(tt.status); got != tt.want {
+			t.Errorf("%s matching %d: got %v, want %v", tt.matcher, tt.status, got, tt.want)
+		}
+	}
+	if _, err := ParseStatusMatcher("399-200"); err == nil {
+		t.Errorf("expected an error for an inverted range")
+	}
+}
+
+// Test that only 2xx status codes are accepted by default
+func TestDefaultStatus(t *testing.T) {
+	var a *Assertions
+	if reason := a.CheckStatus(200); reason != "" {
+		t.Errorf("200 should be accepted, got %q", reason)
+	}
+	if reason := a.CheckStatus(301); reason == "" {
+		t.Errorf("301 should not be accepted")
+	}
+}
+
+// Test the assertions on the response body
+func TestCheckBody(t *testing.T) {
+	body := []byte(`{"status": "ok", "data": {"items": [{"id": 1}, {"id": 2}]}, "healthy": true}`)
+	tests := []struct {
+		name       string
+		assertions Assertions
+		fail       bool
+	}{
+		{"contains", Assertions{BodyContains: []string{`"ok"`}}, false},
+		{"missing keyword", Assertions{BodyContains: []string{"welcome"}}, true},
+		{"forbidden keyword", Assertions{BodyNotContains: []string{"healthy"}}, true},
+		{"regex", Assertions{BodyMatches: []*regexp.Regexp{regexp.MustCompile(`"id":\s*2`)}}, false},
+		{"json string", Assertions{JSON: []JSONAssertion{{Path: "status", Equals: "ok"}}}, false},
+		{"json number in array", Assertions{JSON: []JSONAssertion{{Path: "data.items.1.id", Equals: "2"}}}, false},
+		{"json bool", Assertions{JSON: []JSONAssertion{{Path: "healthy", Equals: "false"}}}, true},
+		{"json exists", Assertions{JSON: []JSONAssertion{{Path: "data.items", Exists: true}}}, false},
+		{"json missing path", Assertions{JSON: []JSONAssertion{{Path: "data.items.5", Exists: true}}}, true},
+	}
+	for _, tt := range tests {
+		reason := tt.assertions.CheckBody(body)
+		if (reason != "") != tt.fail {
+			t.Errorf("%s: got reason %q, want failure %v", tt.name, reason, tt.fail)
+		}
+	}
+	a := Assertions{JSON: []JSONAssertion{{Path: "status", Exists: true}}}
+	if reason := a.CheckBody([]byte("<html>Internal error</html>")); reason == "" {
+		t.Errorf("non json body should fail the json assertions")
+	}
+}
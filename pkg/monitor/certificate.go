@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// Days before the expiry of a certificate that the alert fires, when the website does not define it
+const DefaultCertificateExpiryDays = 14
+
+// Extracts the certificate of a successful https request.
+// The chain has already been verified by the transport
+func certificateFromState(cs *tls.ConnectionState) *info.Certificate {
+	if cs == nil || len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := info.NewCertificate(cs.PeerCertificates[0])
+	cert.Trusted = len(cs.VerifiedChains) > 0
+	cert.HostnameMatch = cert.Trusted
+	return cert
+}
+
+// Reports whether the request failed during the verification of the certificate
+func isCertificateError(err error) bool {
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &hostnameErr) || errors.As(err, &authorityErr) || errors.As(err, &invalidErr)
+}
+
+// Connects to the website without verifying its certificate,
+// in order to describe why the verification failed
+func inspectCertificate(ctx context.Context, rawurl string) (*info.Certificate, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	cs, err := handshake(ctx, net.JoinHostPort(u.Hostname(), port), nil)
+	if err != nil {
+		return nil, err
+	}
+	return verifyChain(cs.PeerCertificates, u.Hostname(), time.Now()), nil
+}
+
+// Connects to the address and completes a TLS handshake without verifying the certificate.
+// The phases of the connection are recorded by the tracer, when given
+func handshake(ctx context.Context, address string, t *tracer) (*tls.ConnectionState, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dial(ctx, address, t)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if t != nil {
+		t.set(&t.tlsStart)
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	if t != nil {
+		t.set(&t.tlsDone)
+	}
+	cs := tlsConn.ConnectionState()
+	return &cs, nil
+}
+
+// Verifies the chain against the system roots and the hostname separately
+func verifyChain(chain []*x509.Certificate, host string, now time.Time) *info.Certificate {
+	if len(chain) == 0 {
+		return nil
+	}
+	leaf := chain[0]
+	cert := info.NewCertificate(leaf)
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	cert.Trusted = err == nil
+	if err != nil {
+		cert.Error = err.Error()
+	}
+	if err := leaf.VerifyHostname(host); err == nil {
+		cert.HostnameMatch = true
+	} else if cert.Error == "" {
+		cert.Error = err.Error()
+	}
+	return cert
+}
@@ -0,0 +1,36 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test that the certificate of a self-signed server is captured and reported as untrusted
+func TestUntrustedCertificate(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	m := NewMonitor()
+	wb := Website{Url: ts.URL, Interval: 1000, Timeout: time.Second}
+	m.monitorOnce(context.Background(), wb)
+
+	stats := m.StatsPerWebsite[ts.URL]
+	if stats == nil || stats.Certificate == nil {
+		t.Fatalf("expected the certificate to be captured")
+	}
+	if stats.Certificate.Trusted {
+		t.Errorf("a self-signed certificate should not be trusted")
+	}
+	if !stats.Certificate.HostnameMatch {
+		t.Errorf("the test certificate should be valid for %s", ts.URL)
+	}
+	if stats.AlertInfo().SuccessfulResponses != 0 {
+		t.Errorf("a request with an untrusted certificate should fail")
+	}
+	if stats.CertificateAlert.State.String() != "INVALID" {
+		t.Errorf("got certificate state %v, want INVALID", stats.CertificateAlert.State)
+	}
+}
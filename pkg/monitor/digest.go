@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/notify"
+)
+
+// How often the monitor checks whether a digest is due
+const digestCheck = time.Minute
+
+// The period a digest summarizes
+const DigestPeriod = 24 * time.Hour
+
+// Sends the digests of the notifiers when they are due, until Stop is called
+func (m *Monitor) digestLoop() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(digestCheck)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.sendDigests(now)
+		}
+	}
+}
+
+// Sends the digests that are due at the given time
+func (m *Monitor) sendDigests(now time.Time) {
+	type pending struct {
+		name string
+		notify.Digester
+		digest notify.Digest
+	}
+	var due []pending
+	m.mutex.Lock()
+	for name, n := range m.notifiers {
+		d, ok := n.(notify.Digester)
+		if !ok {
+			continue
+		}
+		next, ok := m.digestDue[name]
+		if !ok {
+			next = d.NextDigest(now)
+			m.digestDue[name] = next
+		}
+		if next.IsZero() || now.Before(next) {
+			continue
+		}
+		m.digestDue[name] = d.NextDigest(now)
+		var urls []string
+		for _, wb := range m.Wbs {
+			if wb.notifies(name) {
+				urls = append(urls, wb.Url)
+			}
+		}
+		due = append(due, pending{name: name, Digester: d, digest: m.digest(urls, now.Add(-DigestPeriod), now)})
+	}
+	m.mutex.Unlock()
+
+	for _, p := range due {
+		if err := p.SendDigest(p.digest); err != nil {
+			fmt.Fprintf(os.Stderr, "notifier %s: digest: %v\n", p.name, err)
+		}
+	}
+}
+
+// Reports whether the website is routed to the notifier
+func (wb Website) notifies(name string) bool {
+	if len(wb.Notify) == 0 {
+		return true
+	}
+	for _, n := range wb.Notify {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Digest summarizes the websites over the period from..to, from their history and their incidents
+func (m *Monitor) Digest(urls []string, from, to time.Time) notify.Digest {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.digest(urls, from, to)
+}
+
+// The mutex must be held
+func (m *Monitor) digest(urls []string, from, to time.Time) notify.Digest {
+	d := notify.Digest{From: from, To: to}
+	for _, url := range urls {
+		w := notify.WebsiteDigest{Website: url}
+		if stats, ok := m.StatsPerWebsite[url]; ok {
+			b := stats.History.Summary(from, to)
+			w.Checks = b.Count
+			w.Availability = b.Availability()
+			if b.Successes > 0 {
+				w.P90 = b.Percentile(90)
+			}
+			a := stats.AlertInfo().Alert
+			for _, in := range a.Incidents {
+				if in.Start.Before(to) && (in.Ongoing() || in.End.After(from)) {
+					w.Incidents = append(w.Incidents, in)
+				}
+			}
+			// The time before monitoring started is neither uptime nor downtime
+			since := from
+			if a.Since.After(since) {
+				since = a.Since
+			}
+			w.Stats = a.Stats(since, to)
+		}
+		d.Websites = append(d.Websites, w)
+	}
+	return d
+}
@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// dnsProber resolves the hostname of the website.
+// The body assertions are evaluated against the resolved addresses, one per line
+type dnsProber struct {
+	wb Website
+}
+
+func (p *dnsProber) Probe(ctx context.Context) *info.Response {
+	resolver := net.DefaultResolver
+	if p.wb.Resolver != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				dialer := &net.Dialer{}
+				return dialer.DialContext(ctx, network, p.wb.Resolver)
+			},
+		}
+	}
+	sample := &info.Response{}
+	t := &tracer{start: time.Now()}
+	t.set(&t.dnsStart)
+	addrs, err := resolver.LookupHost(ctx, p.wb.Url)
+	if err == nil {
+		t.set(&t.dnsDone)
+		if sample.Reason = p.wb.Assertions.CheckBody([]byte(strings.Join(addrs, "\n"))); sample.Reason != "" {
+			sample.Failure = info.AssertionFailure
+		}
+	}
+	return finishSample(sample, t, err)
+}
@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// Classifies the error of a failed request.
+// The tracer is used to find the phase of the request that timed out
+func classifyError(err error, t *tracer) info.FailureClass {
+	if isTimeout(err) {
+		return t.timedOutPhase()
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return info.DNSFailure
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return info.ConnectionRefused
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return info.ConnectionReset
+	}
+
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &hostnameErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &invalidErr) || errors.As(err, &recordErr) {
+		return info.TLSError
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "remote error" {
+		// Alerts sent by the server during the handshake
+		return info.TLSError
+	}
+	return info.OtherFailure
+}
+
+// Reports whether the request failed because the response did not arrive in time
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,55 @@
+package monitor
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// Test the classification of failed requests
+func TestClassifyFailures(t *testing.T) {
+	// A port that no one listens to
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := "http://" + l.Addr().String()
+	l.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	tests := []struct {
+		wb   Website
+		want info.FailureClass
+	}{
+		{Website{Url: refused, Interval: 1000, Timeout: time.Second}, info.ConnectionRefused},
+		{Website{Url: slow.URL, Interval: 1000, Timeout: 100 * time.Millisecond}, info.ResponseTimeout},
+		{Website{Url: failing.URL, Interval: 1000, Timeout: time.Second}, info.AssertionFailure},
+	}
+	for _, tt := range tests {
+		res := probeOnce(t, tt.wb)
+		if res.Failure != tt.want {
+			t.Errorf("%s: got %q, want %q (%s)", tt.wb.Url, res.Failure, tt.want, res.Reason)
+		}
+		if res.Success {
+			t.Errorf("%s: should not be successful", tt.wb.Url)
+		}
+	}
+
+	res := probeOnce(t, Website{Url: failing.URL, Interval: 1000, Timeout: time.Second})
+	if res.Status != http.StatusInternalServerError {
+		t.Errorf("got status %d, want the real status code", res.Status)
+	}
+}
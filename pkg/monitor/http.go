@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// Maximum number of redirects followed, when the website does not define it
+const DefaultMaxRedirects = 10
+
+// RedirectPolicy defines how the redirects of a website are handled.
+// When redirects are not followed, the 3xx response is the final one
+type RedirectPolicy struct {
+	Follow bool
+	// Maximum number of redirects to follow
+	Max int
+	// When set, the chain of redirects must end at this url
+	FinalUrl string
+}
+
+// httpProber sends the request of a website,
+// following the redirects according to its policy
+type httpProber struct {
+	wb Website
+}
+
+func (p *httpProber) Probe(ctx context.Context) *info.Response {
+	wb := p.wb
+	maxRedirects := wb.Redirects.Max
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	sample := &info.Response{}
+	timing := &info.Timing{}
+	method, target, body := wb.Method, wb.Url, wb.Body
+	start := time.Now()
+	for {
+		req, err := wb.newRequest(method, target, body)
+		if err != nil {
+			sample.Failure = info.OtherFailure
+			sample.Reason = err.Error()
+			break
+		}
+		t := &tracer{}
+		req = req.WithContext(httptrace.WithClientTrace(ctx, t.clientTrace()))
+		t.start = time.Now()
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			sample.Failure = classifyError(err, t)
+			sample.Reason = err.Error()
+			if sample.Failure == info.TLSError && isCertificateError(err) {
+				// Within what is left of the timeout of the check, and canceled by Stop
+				sample.Certificate, _ = inspectCertificate(ctx, target)
+			}
+			break
+		}
+
+		location, _ := res.Location()
+		if wb.Redirects.Follow && isRedirect(res.StatusCode) && location != nil {
+			// Drain the body, so that the connection can be reused
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+			hop := t.timing(time.Now())
+			addTiming(timing, hop)
+			sample.Redirects = append(sample.Redirects, info.Redirect{
+				Url:      target,
+				Status:   res.StatusCode,
+				Duration: hop.Total,
+			})
+			if len(sample.Redirects) > maxRedirects {
+				sample.Status = res.StatusCode
+				sample.Failure = info.TooManyRedirects
+				sample.Reason = fmt.Sprintf("stopped after %d redirects", maxRedirects)
+				break
+			}
+			target = location.String()
+			// Only 307 and 308 preserve the method and the body of the request
+			if res.StatusCode != http.StatusTemporaryRedirect && res.StatusCode != http.StatusPermanentRedirect &&
+				method != http.MethodGet && method != http.MethodHead {
+				method, body = http.MethodGet, ""
+			}
+			continue
+		}
+
+		sample.Status = res.StatusCode
+		sample.Reason = wb.Assertions.CheckStatus(res.StatusCode)
+		if sample.Reason != "" {
+			sample.Failure = info.AssertionFailure
+		} else if wb.Redirects.FinalUrl != "" && !sameUrl(target, wb.Redirects.FinalUrl) {
+			sample.Failure = info.AssertionFailure
+			sample.Reason = fmt.Sprintf("final url %s, expected %s", target, wb.Redirects.FinalUrl)
+		} else if wb.Assertions.NeedsBody() {
+			content, err := ioutil.ReadAll(io.LimitReader(res.Body, MaxBodySize))
+			if err != nil {
+				sample.Failure = classifyError(err, t)
+				sample.Reason = fmt.Sprintf("unable to read the body: %v", err)
+			} else if sample.Reason = wb.Assertions.CheckBody(content); sample.Reason != "" {
+				sample.Failure = info.AssertionFailure
+			}
+		}
+		// Drain the body, so that the connection can be reused
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		end := time.Now()
+
+		hop := t.timing(end)
+		addTiming(timing, hop)
+		timing.Reused = hop.Reused
+		timing.Total = end.Sub(start)
+		sample.Delay = t.firstByteSince(start)
+		sample.Timing = timing
+		sample.Certificate = certificateFromState(res.TLS)
+		if len(sample.Redirects) > 0 {
+			sample.Redirects = append(sample.Redirects, info.Redirect{
+				Url:      target,
+				Status:   res.StatusCode,
+				Duration: hop.Total,
+			})
+		}
+		break
+	}
+	sample.Success = sample.Reason == ""
+	return sample
+}
+
+// Creates the request described by the website's headers, for the given method, url and body
+func (wb Website) newRequest(method, target, body string) (*http.Request, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	sameHost := true
+	if u, err := url.Parse(wb.Url); err == nil {
+		sameHost = u.Host == req.URL.Host
+	}
+	for key, val := range wb.Headers {
+		if strings.EqualFold(key, "Host") {
+			if sameHost {
+				req.Host = val
+			}
+			continue
+		}
+		// Credentials are not sent to other hosts while following redirects
+		if !sameHost && (strings.EqualFold(key, "Authorization") || strings.EqualFold(key, "Cookie")) {
+			continue
+		}
+		req.Header.Set(key, val)
+	}
+	return req, nil
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// Compares two urls ignoring a trailing slash in the path
+func sameUrl(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// Adds the phases of a single request to the phases of the whole chain
+func addTiming(total, hop *info.Timing) {
+	total.DNS += hop.DNS
+	total.Connect += hop.Connect
+	total.TLS += hop.TLS
+	total.Server += hop.Server
+	total.Transfer += hop.Transfer
+}
@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// Test the redirect policies against a chain of two redirects
+func TestRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.RedirectHandler("/https", http.StatusMovedPermanently))
+	mux.Handle("/https", http.RedirectHandler("/www", http.StatusFound))
+	mux.HandleFunc("/www", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/loop", http.RedirectHandler("/loop", http.StatusFound))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// Redirects are not followed by default
+	res := probeOnce(t, Website{Url: ts.URL + "/", Timeout: time.Second})
+	if res.Status != http.StatusMovedPermanently || res.Success || len(res.Redirects) != 0 {
+		t.Errorf("got status %d, success %v, %d redirects", res.Status, res.Success, len(res.Redirects))
+	}
+
+	res = probeOnce(t, Website{Url: ts.URL + "/", Timeout: time.Second, Redirects: RedirectPolicy{Follow: true}})
+	if res.Status != http.StatusOK || !res.Success {
+		t.Errorf("got status %d (%s), want 200", res.Status, res.Reason)
+	}
+	if len(res.Redirects) != 3 || res.Redirects[2].Url != ts.URL+"/www" {
+		t.Errorf("unexpected chain %+v", res.Redirects)
+	}
+
+	res = probeOnce(t, Website{Url: ts.URL + "/", Timeout: time.Second, Redirects: RedirectPolicy{Follow: true, FinalUrl: ts.URL + "/other"}})
+	if res.Success || res.Failure != info.AssertionFailure {
+		t.Errorf("a different final url should fail, got %q", res.Failure)
+	}
+
+	res = probeOnce(t, Website{Url: ts.URL + "/loop", Timeout: time.Second, Redirects: RedirectPolicy{Follow: true, Max: 3}})
+	if res.Failure != info.TooManyRedirects || len(res.Redirects) != 4 {
+		t.Errorf("got %q after %d redirects", res.Failure, len(res.Redirects))
+	}
+}
+
+// Test that the method, the headers and the body of the website are sent
+func TestRequest(t *testing.T) {
+	var method, auth, contentType, body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, auth, contentType = r.Method, r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer ts.Close()
+
+	res := probeOnce(t, Website{
+		Url:     ts.URL,
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Authorization": "Bearer token", "Content-Type": "application/json"},
+		Body:    `{"ping": true}`,
+		Timeout: time.Second,
+	})
+	if !res.Success {
+		t.Fatalf("got %s", res.Reason)
+	}
+	if method != http.MethodPost || auth != "Bearer token" || contentType != "application/json" || body != `{"ping": true}` {
+		t.Errorf("got %s with authorization %q, content type %q and body %q", method, auth, contentType, body)
+	}
+}
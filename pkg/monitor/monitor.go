@@ -1,35 +1,74 @@
 package monitor
 
 import (
+	"context"
 	"fmt"
-	"net/http"
-	"net/http/httptrace"
-	"strings"
+	"reflect"
 	"sync"
 	"time"
 
 	"github.com/iwita/monitoring-website-stats/pkg/alert"
 	"github.com/iwita/monitoring-website-stats/pkg/info"
+	"github.com/iwita/monitoring-website-stats/pkg/notify"
 )
 
 const MaxInt = int(^uint(0) >> 1)
 
+// Timeout of a request, when the website does not define one
+const DefaultTimeout = 10 * time.Second
+
 type Website struct {
+	// The kind of check, one of the probe types (by default http)
+	Type string
+	// The url of http websites, or the address (host:port) or hostname of the other probe types
 	Url      string
 	Interval float64
-	Timer    *time.Ticker
-	Res10m   *info.Result
-	Res1h    *info.Result
+	Method   string
+	Headers  map[string]string
+	Body     string
+	Timeout  time.Duration
+	// Checks the response must pass in order to be considered successful
+	Assertions *Assertions
+	// How long before the expiry of the certificate the alert fires
+	CertificateExpiry time.Duration
+	Redirects         RedirectPolicy
+	// The DNS server (host:port) queried by dns probes, by default the system resolver
+	Resolver string
+	Apdex    Apdex
+	// The service level objectives of the website, with their burn-rate alerts
+	SLOs []SLO
+	// The names of the notifiers of the alerts of the website, all of them when empty
+	Notify []string
+	// Performs the check, created by NewProber
+	Prober Prober
+	Timer  *time.Ticker
 }
 
 type Websites []Website
 
 // The Statistics type is a struct that includes different durations of statistics information
 type Statistics struct {
-	TwoMinutesInfo *info.Info
-	TenMinutesInfo *info.Info
-	OneHourInfo    *info.Info
-	OverallInfo    *info.Info
+	// One Info per window of the monitor, in the same order
+	Windows []*info.Info
+	// The statistics since monitoring of the website started, stored in a sketch
+	OverallInfo *info.Info
+	// The history of the website in minutes, hours and days, see info.DefaultResolutions
+	History *info.Rollup
+	// The error budget and burn-rate alert of every objective, in the configured order
+	SLOs        []*SLOTracker
+	names       map[string]int
+	alertWindow *info.Info
+
+	// The most recently inspected certificate, nil for plain http
+	Certificate      *info.Certificate
+	CertificateAlert *alert.CertificateAlert
+
+	// The redirects followed by the most recent request
+	Redirects []info.Redirect
+
+	// The results of the windows most recently calculated by a snapshot, and when
+	cached    []*info.Result
+	refreshed []time.Time
 }
 
 // Monitor type is the main type of this package
@@ -40,109 +79,311 @@ type Monitor struct {
 	done            chan bool
 	mutex           *sync.Mutex
 	Alert           *alert.Alert
+	// The time windows of the statistics, and the one the alert is evaluated on
+	Windows     []Window
+	AlertWindow string
+	// The windows the snapshots compare, nil when no trend is calculated
+	trend *trend
+	// The notifiers of the alert transitions, by name, and when their next digest is due
+	notifiers map[string]notify.Notifier
+	digestDue map[string]time.Time
+	// The events waiting to be delivered to each notifier
+	queues map[string]*queue
+	// The goroutine monitoring each website, once Exec is called
+	workers map[string]*worker
+	running bool
+	wg      sync.WaitGroup
+	// Canceled by Stop, in order to abort the requests in flight
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+}
+
+// worker is the handle of the goroutine that monitors a single website
+type worker struct {
+	cancel context.CancelFunc
+	// Receives the new definition of the website, when its configuration changes
+	updates chan Website
 }
 
 // Initialize the Monitor, by setting the default values and allocating space
 func NewMonitor() *Monitor {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Monitor{
+		ctx:             ctx,
+		cancel:          cancel,
 		Wbs:             make(Websites, 0),
 		UrlToWebsite:    make(map[string]Website, 0),
 		done:            make(chan bool),
 		StatsPerWebsite: make(map[string]*Statistics, 0),
 		mutex:           &sync.Mutex{},
 		Alert:           alert.NewAlert(0.8),
+		workers:         make(map[string]*worker, 0),
+		digestDue:       make(map[string]time.Time, 0),
+		Windows:         DefaultWindows,
+		AlertWindow:     DefaultAlertWindow,
 	}
 }
 
+// Exec monitors every website until Stop is called
 func (m *Monitor) Exec() {
 	m.exec()
 }
 
+// Stop stops monitoring every website, cancels the requests in flight
+// and waits until all the goroutines have returned and the pending notifications are delivered.
+// The statistics remain available afterwards
+func (m *Monitor) Stop() {
+	m.stopOnce.Do(func() {
+		m.mutex.Lock()
+		m.running = false
+		m.cancel()
+		close(m.done)
+		m.closeQueues()
+		m.mutex.Unlock()
+	})
+	m.wg.Wait()
+}
+
 func (m *Monitor) exec() {
-	var wg sync.WaitGroup
+	m.mutex.Lock()
+	if m.ctx.Err() != nil {
+		// Already stopped
+		m.mutex.Unlock()
+		return
+	}
+	m.running = true
+	m.wg.Add(1)
+	go m.digestLoop()
 	// For each website, create a new goroutine
 	for _, wb := range m.Wbs {
-		wg.Add(1)
-		go m.manageSingleWebsite(wb)
-
+		if _, ok := m.workers[wb.Url]; !ok {
+			m.start(wb)
+		}
 	}
-	wg.Wait()
+	m.mutex.Unlock()
+	m.wg.Wait()
 	//fmt.Println("After wait")
 
 }
 
+// Starts the goroutine of a website. The mutex must be held
+func (m *Monitor) start(wb Website) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	w := &worker{
+		cancel:  cancel,
+		updates: make(chan Website, 1),
+	}
+	m.workers[wb.Url] = w
+	m.wg.Add(1)
+	go m.manageSingleWebsite(ctx, wb, w.updates)
+}
+
+// Reload replaces the monitored websites with the given ones.
+// Websites are identified by their url:
+//   - new websites start being monitored
+//   - removed websites stop being monitored, and their statistics are dropped
+//   - changed websites continue with the new configuration (e.g. interval),
+//     keeping their statistics
+//   - unchanged websites are not affected
+func (m *Monitor) Reload(wbs Websites) (added, removed, changed []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current := make(map[string]Website, len(m.Wbs))
+	for _, wb := range m.Wbs {
+		current[wb.Url] = wb
+	}
+	next := make(Websites, 0, len(wbs))
+	for _, wb := range wbs {
+		old, ok := current[wb.Url]
+		if !ok {
+			added = append(added, wb.Url)
+			if m.running {
+				m.start(wb)
+			}
+			next = append(next, wb)
+			continue
+		}
+		delete(current, wb.Url)
+		if wb.sameConfig(old) {
+			// Keep the running ticker of the website
+			wb.Timer.Stop()
+			next = append(next, old)
+			continue
+		}
+		changed = append(changed, wb.Url)
+		if stats, ok := m.StatsPerWebsite[wb.Url]; ok && wb.Interval != old.Interval {
+			stats.resize(wb.interval())
+		}
+		if stats, ok := m.StatsPerWebsite[wb.Url]; ok && wb.Apdex != old.Apdex {
+			stats.setApdex(wb.Apdex)
+		}
+		if stats, ok := m.StatsPerWebsite[wb.Url]; ok && wb.CertificateExpiry != old.CertificateExpiry {
+			// Applied on the next inspection of the certificate
+			stats.CertificateAlert.ExpiryThreshold = wb.certificateExpiry()
+		}
+		if stats, ok := m.StatsPerWebsite[wb.Url]; ok && !reflect.DeepEqual(wb.SLOs, old.SLOs) {
+			stats.setSLOs(wb.SLOs, wb.interval())
+		}
+		if w, ok := m.workers[wb.Url]; ok {
+			// Replace any update that was not received yet, stopping its ticker
+			select {
+			case pending := <-w.updates:
+				pending.Timer.Stop()
+			default:
+			}
+			w.updates <- wb
+		} else {
+			old.Timer.Stop()
+		}
+		next = append(next, wb)
+	}
+	for _, wb := range m.Wbs {
+		if _, ok := current[wb.Url]; !ok {
+			continue
+		}
+		removed = append(removed, wb.Url)
+		if w, ok := m.workers[wb.Url]; ok {
+			w.cancel()
+			delete(m.workers, wb.Url)
+		} else {
+			wb.Timer.Stop()
+		}
+		delete(m.StatsPerWebsite, wb.Url)
+	}
+	m.Wbs = next
+	return added, removed, changed
+}
+
+// Reports whether two definitions of a website describe the same check
+func (wb Website) sameConfig(other Website) bool {
+	a, b := wb, other
+	a.Timer, b.Timer = nil, nil
+	a.Prober, b.Prober = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// Sends a single request to every website and waits until all of them are completed
+func (m *Monitor) Once() {
+	var wg sync.WaitGroup
+	for _, wb := range m.Wbs {
+		wg.Add(1)
+		go func(wb Website) {
+			defer wg.Done()
+			m.monitorOnce(m.ctx, wb)
+		}(wb)
+	}
+	wg.Wait()
+}
+
 // Go routine executed for each website
-// Waits until the ticker reaches the interval's predefined value
-func (m *Monitor) manageSingleWebsite(wb Website) {
+// Waits until the ticker reaches the interval's predefined value,
+// until the website is removed
+func (m *Monitor) manageSingleWebsite(ctx context.Context, wb Website, updates <-chan Website) {
+	defer m.wg.Done()
 	for {
 		select {
 		case <-m.done:
+			wb.Timer.Stop()
 			return
+		case <-ctx.Done():
+			wb.Timer.Stop()
+			return
+		case next := <-updates:
+			// Continue with the ticker of the new interval
+			wb.Timer.Stop()
+			wb = next
 		case <-wb.Timer.C:
-			m.monitorOnce(wb)
+			m.monitorOnce(ctx, wb)
 		}
 	}
 }
 
 // It is called when a new request needs to be sent to a website
-func (m *Monitor) monitorOnce(wb Website) {
-	var start time.Time
-	var elapsedTime time.Duration
-	req, err := http.NewRequest("GET", wb.Url, nil)
-	//fmt.Println("After New Request")
-	if err != nil {
-		fmt.Printf("Error while sending the request to %v : %v", wb.Url, err)
-		return
-	}
-	trace := &httptrace.ClientTrace{
-		//ConnectStart: func(network, addr string) { connectStart = time.Now() },
-		//DNSStart:     func(dnsinfo httptrace.DNSStartInfo) { dnsStart = time.Now() },
-		//DNSDone:      func(dnsinfo httptrace.DNSDoneInfo) { dnsLookup = time.Since(dnsStart) },
-		//ConnectDone:  func(network, addr string, err error) { connectionEstablishment = time.Since(connectStart) },
-		GotFirstResponseByte: func() {
-			elapsedTime = time.Since(start)
-		},
-	}
-	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-	start = time.Now()
-	res, err := http.DefaultTransport.RoundTrip(req)
-	var sc int
-	// TODO:
-	// when the website is unavailable don't just return
-	if err != nil {
-		fmt.Println(err)
-		elapsedTime = 0 * time.Millisecond
-		if strings.Contains(err.Error(), "i/o timeout") {
-			sc = 408
-		} else if strings.Contains(err.Error(), "no such host") {
-			sc = 502
-		}
-	} else {
-		sc = res.StatusCode
+func (m *Monitor) monitorOnce(parent context.Context, wb Website) {
+	ctx, cancel := context.WithTimeout(parent, wb.timeout())
+	defer cancel()
+	p := wb.Prober
+	if p == nil {
+		p = &httpProber{wb: wb}
 	}
+	start := time.Now()
+	sample := p.Probe(ctx)
+	sample.Time = start
 
 	m.mutex.Lock()
-	m.addStatistics(wb, elapsedTime, sc)
+	// The website may have been removed, or the monitor stopped,
+	// while waiting for the response
+	if parent.Err() != nil {
+		m.mutex.Unlock()
+		return
+	}
+	// Queued rather than delivered here, a slow notifier would delay the next check
+	// and the delay would count as a gap in the availability
+	m.enqueue(wb, m.addStatistics(wb, sample))
 	m.mutex.Unlock()
 }
 
-// Adds the newly extracted metrics into the statistics of the website
-func (m *Monitor) addStatistics(wb Website, elapsedTime time.Duration, status int) {
+// Returns the interval between the checks, which is configured in milliseconds
+func (wb Website) interval() time.Duration {
+	return time.Duration(wb.Interval * float64(time.Millisecond))
+}
+
+// Returns how long before the expiry of the certificate the alert fires
+func (wb Website) certificateExpiry() time.Duration {
+	if wb.CertificateExpiry <= 0 {
+		return DefaultCertificateExpiryDays * 24 * time.Hour
+	}
+	return wb.CertificateExpiry
+}
+
+// Returns the timeout of a single check
+func (wb Website) timeout() time.Duration {
+	if wb.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return wb.Timeout
+}
+
+// Adds the newly extracted metrics into the statistics of the website,
+// and returns the transitions of its alerts
+func (m *Monitor) addStatistics(wb Website, sample *info.Response) []notify.Event {
 
 	// Handle the case, where there are no previous metrics stored
 	if _, ok := m.StatsPerWebsite[wb.Url]; !ok {
-		m.StatsPerWebsite[wb.Url] = &Statistics{
-			TwoMinutesInfo: info.NewInfo(time.Duration(2)*time.Minute, time.Duration(wb.Interval)*time.Millisecond, true),
-			TenMinutesInfo: info.NewInfo(time.Duration(10)*time.Minute, time.Duration(wb.Interval)*time.Millisecond, false),
-			OneHourInfo:    info.NewInfo(time.Duration(1)*time.Hour, time.Duration(wb.Interval)*time.Millisecond, false),
-			//OverallInfo:    info.NewInfo(time.Duration(0)*time.Hour, time.Duration(wb.Interval)*time.Millisecond, false),
-		}
+		stats := m.newStatistics(wb)
+		stats.CertificateAlert = alert.NewCertificateAlert(wb.certificateExpiry())
+		m.StatsPerWebsite[wb.Url] = stats
 	}
+	before := m.StatsPerWebsite[wb.Url].alertStates()
 
-	m.StatsPerWebsite[wb.Url].TwoMinutesInfo.Update(status, elapsedTime)
-	m.StatsPerWebsite[wb.Url].TenMinutesInfo.Update(status, elapsedTime)
-	m.StatsPerWebsite[wb.Url].OneHourInfo.Update(status, elapsedTime)
-	//m.StatsPerWebsite[wb.Url].OverallInfo.Update(status, elapsedTime)
+	for _, window := range m.StatsPerWebsite[wb.Url].Windows {
+		window.Update(sample)
+	}
+	m.StatsPerWebsite[wb.Url].OverallInfo.Update(sample)
+	m.StatsPerWebsite[wb.Url].History.Add(sample)
+	for _, slo := range m.StatsPerWebsite[wb.Url].SLOs {
+		slo.update(sample)
+	}
+
+	if cert := sample.Certificate; cert != nil {
+		m.StatsPerWebsite[wb.Url].Certificate = cert
+		m.StatsPerWebsite[wb.Url].CertificateAlert.Update(cert.NotAfter, cert.Trusted, cert.HostnameMatch, cert.Error)
+	}
+	m.StatsPerWebsite[wb.Url].Redirects = sample.Redirects
+	return m.StatsPerWebsite[wb.Url].transitions(wb.Url, before, sample.Time)
+}
+
+// Adapts every time window to a new interval of the website
+func (s *Statistics) resize(interval time.Duration) {
+	for _, window := range s.Windows {
+		window.Resize(interval)
+	}
+	s.OverallInfo.Resize(interval)
+	for _, slo := range s.SLOs {
+		slo.resize(interval)
+	}
 }
 
 func (m *Monitor) printStats() {
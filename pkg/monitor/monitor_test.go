@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+)
+
+func newTestWebsite(url string, interval float64) Website {
+	return Website{
+		Url:      url,
+		Interval: interval,
+		Timeout:  time.Second,
+		Timer:    time.NewTicker(time.Duration(interval) * time.Millisecond),
+	}
+}
+
+// Test that reloading keeps the statistics of the websites that remain
+func TestReload(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	m := NewMonitor()
+	m.Wbs = append(m.Wbs, newTestWebsite(ts.URL+"/a", 20), newTestWebsite(ts.URL+"/b", 20))
+	go m.Exec()
+	time.Sleep(100 * time.Millisecond)
+
+	added, removed, changed := m.Reload(Websites{newTestWebsite(ts.URL+"/a", 40), newTestWebsite(ts.URL+"/c", 20)})
+	if len(added) != 1 || len(removed) != 1 || len(changed) != 1 {
+		t.Fatalf("got added %v, removed %v, changed %v", added, removed, changed)
+	}
+
+	m.mutex.Lock()
+	before := m.StatsPerWebsite[ts.URL+"/a"].AlertInfo().TotalResponses
+	m.mutex.Unlock()
+	if before == 0 {
+		t.Fatalf("expected responses before the reload")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	m.mutex.Lock()
+	if _, ok := m.StatsPerWebsite[ts.URL+"/b"]; ok {
+		t.Errorf("the statistics of a removed website should be dropped")
+	}
+	if m.StatsPerWebsite[ts.URL+"/a"].AlertInfo().TotalResponses <= before {
+		t.Errorf("the statistics of a changed website should be kept")
+	}
+	if stats := m.StatsPerWebsite[ts.URL+"/a"]; stats.OverallInfo.TotalResponses != stats.AlertInfo().TotalResponses {
+		t.Errorf("got %d responses since the start, want %d", stats.OverallInfo.TotalResponses, stats.AlertInfo().TotalResponses)
+	}
+	if _, ok := m.StatsPerWebsite[ts.URL+"/c"]; !ok {
+		t.Errorf("an added website should be monitored")
+	}
+	m.mutex.Unlock()
+
+	// Reloading the same configuration changes nothing
+	added, removed, changed = m.Reload(Websites{newTestWebsite(ts.URL+"/a", 40), newTestWebsite(ts.URL+"/c", 20)})
+	if len(added)+len(removed)+len(changed) != 0 {
+		t.Errorf("got added %v, removed %v, changed %v", added, removed, changed)
+	}
+}
+
+// Test that an update replaced before the worker received it does not leak its ticker,
+// and that the certificate expiry of a changed website is applied
+func TestReloadPending(t *testing.T) {
+	m := NewMonitor()
+	wb := newTestWebsite("http://127.0.0.1/a", 5)
+	m.Wbs = append(m.Wbs, wb)
+	m.StatsPerWebsite[wb.Url] = m.newStatistics(wb)
+	m.StatsPerWebsite[wb.Url].CertificateAlert = alert.NewCertificateAlert(wb.certificateExpiry())
+	// A worker that does not receive its updates
+	m.workers[wb.Url] = &worker{cancel: func() {}, updates: make(chan Website, 1)}
+
+	first := newTestWebsite(wb.Url, 6)
+	m.Reload(Websites{first})
+	second := newTestWebsite(wb.Url, 7)
+	second.CertificateExpiry = 30 * 24 * time.Hour
+	m.Reload(Websites{second})
+	defer second.Timer.Stop()
+
+	select {
+	case <-first.Timer.C:
+		t.Errorf("the ticker of the replaced update should be stopped")
+	case <-time.After(30 * time.Millisecond):
+	}
+	if got := m.StatsPerWebsite[wb.Url].CertificateAlert.ExpiryThreshold; got != second.CertificateExpiry {
+		t.Errorf("got certificate expiry %v, want %v", got, second.CertificateExpiry)
+	}
+}
+
+// Test that Stop cancels the requests in flight and returns once every goroutine has exited
+func TestStop(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	m := NewMonitor()
+	wb := newTestWebsite(ts.URL, 10)
+	wb.Timeout = time.Minute
+	m.Wbs = append(m.Wbs, wb)
+	exited := make(chan struct{})
+	go func() {
+		m.Exec()
+		close(exited)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		m.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Stop did not cancel the request in flight")
+	}
+	<-exited
+	if _, ok := m.StatsPerWebsite[ts.URL]; ok {
+		t.Errorf("a canceled request should not be added to the statistics")
+	}
+	// Stopping twice is allowed
+	m.Stop()
+}
+
+// Test that fractions of a millisecond are not truncated
+func TestInterval(t *testing.T) {
+	for _, test := range []struct {
+		interval float64
+		expected time.Duration
+	}{
+		{1000, time.Second},
+		{1.5, 1500 * time.Microsecond},
+		{0.5, 500 * time.Microsecond},
+	} {
+		if got := (Website{Interval: test.interval}).interval(); got != test.expected {
+			t.Errorf("%vms: got %v, expected %v", test.interval, got, test.expected)
+		}
+	}
+}
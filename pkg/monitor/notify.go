@@ -0,0 +1,213 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+	"github.com/iwita/monitoring-website-stats/pkg/notify"
+)
+
+// SetNotifiers replaces the notifiers the websites may be routed to, by name.
+// A website is notified through the notifiers listed in its Notify, or through all of them when it lists none.
+// The notifiers that are notify.Digester also receive a digest of their websites when it is due
+func (m *Monitor) SetNotifiers(notifiers map[string]notify.Notifier) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, wb := range m.Wbs {
+		for _, name := range wb.Notify {
+			if _, ok := notifiers[name]; !ok {
+				return fmt.Errorf("website %s: notifier %q is not defined", wb.Url, name)
+			}
+		}
+	}
+	// The digests of the notifiers that did not change stay due at the same time,
+	// so that a reload just before a digest does not skip it
+	now := time.Now()
+	due := make(map[string]time.Time, len(notifiers))
+	for name, n := range notifiers {
+		d, ok := n.(notify.Digester)
+		if !ok {
+			continue
+		}
+		if next, ok := m.digestDue[name]; ok && reflect.DeepEqual(m.notifiers[name], n) {
+			due[name] = next
+		} else {
+			due[name] = d.NextDigest(now)
+		}
+	}
+	m.notifiers = notifiers
+	m.digestDue = due
+	m.setQueues(notifiers)
+	return nil
+}
+
+// The number of events a notifier may fall behind by, the later ones are dropped
+const queueSize = 100
+
+// queue delivers the events of a notifier in its own goroutine, in order,
+// so that a slow notifier does not delay the checks of the websites
+type queue struct {
+	name string
+	notify.Notifier
+	events chan notify.Event
+	// Closed once every event of the queue is delivered
+	done chan struct{}
+}
+
+// Replaces the queues with one for each notifier. The mutex must be held.
+// The replaced queues are closed, and deliver their pending events before the new ones start
+func (m *Monitor) setQueues(notifiers map[string]notify.Notifier) {
+	old := m.queues
+	m.queues = make(map[string]*queue, len(notifiers))
+	for _, q := range old {
+		close(q.events)
+	}
+	if m.ctx.Err() != nil {
+		// Stopped, nothing is delivered anymore
+		return
+	}
+	for name, n := range notifiers {
+		q := &queue{
+			name:     name,
+			Notifier: n,
+			events:   make(chan notify.Event, queueSize),
+			done:     make(chan struct{}),
+		}
+		m.queues[name] = q
+		m.wg.Add(1)
+		go q.run(&m.wg, old[name])
+	}
+}
+
+// Delivers the events until the queue is closed, after those of the queue it replaced,
+// reporting the failures on the standard error
+func (q *queue) run(wg *sync.WaitGroup, prev *queue) {
+	defer wg.Done()
+	defer close(q.done)
+	if prev != nil {
+		<-prev.done
+	}
+	for e := range q.events {
+		if err := q.Notify(e); err != nil {
+			fmt.Fprintf(os.Stderr, "notifier %s: %v\n", q.name, err)
+		}
+	}
+}
+
+// Closes the queues, which deliver their pending events. The mutex must be held
+func (m *Monitor) closeQueues() {
+	for _, q := range m.queues {
+		close(q.events)
+	}
+	m.queues = nil
+}
+
+// Queues the events for the notifiers of a website, in order of name. The mutex must be held.
+// A notifier that fell behind by queueSize events misses the later ones
+func (m *Monitor) enqueue(wb Website, events []notify.Event) {
+	if len(events) == 0 {
+		return
+	}
+	names := wb.Notify
+	if len(names) == 0 {
+		for name := range m.queues {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		// Websites may be reloaded with a notifier that is not defined yet
+		q, ok := m.queues[name]
+		if !ok {
+			continue
+		}
+		for _, e := range events {
+			select {
+			case q.events <- e:
+			default:
+				fmt.Fprintf(os.Stderr, "notifier %s: %d events pending, dropping the %s event of %s\n", name, queueSize, e.Alert, e.Website)
+			}
+		}
+	}
+}
+
+// alertStates are the states of the alerts of a website, compared before and after a check
+type alertStates struct {
+	availability      alert.State
+	availabilitySince time.Time
+	certificate       alert.CertificateState
+	certificateSince  time.Time
+	slos              map[*SLOTracker]alert.SLOState
+	slosSince         map[*SLOTracker]time.Time
+}
+
+// Returns the current states of the alerts of the website
+func (s *Statistics) alertStates() alertStates {
+	a := s.AlertInfo().Alert
+	st := alertStates{
+		availability:      a.AlertState,
+		availabilitySince: a.StateSince(),
+		certificate:       s.CertificateAlert.State,
+		certificateSince:  s.OverallInfo.Since(),
+		slos:              make(map[*SLOTracker]alert.SLOState, len(s.SLOs)),
+		slosSince:         make(map[*SLOTracker]time.Time, len(s.SLOs)),
+	}
+	if n := len(s.CertificateAlert.Transitions); n > 0 {
+		st.certificateSince = s.CertificateAlert.Transitions[n-1].Time
+	}
+	for _, t := range s.SLOs {
+		st.slos[t] = t.Alert.State
+		st.slosSince[t] = s.OverallInfo.Since()
+		if n := len(t.Alert.Transitions); n > 0 {
+			st.slosSince[t] = t.Alert.Transitions[n-1].Time
+		}
+	}
+	return st
+}
+
+// Returns an event for every alert of the website whose state changed since before
+func (s *Statistics) transitions(url string, before alertStates, t time.Time) []notify.Event {
+	a := s.AlertInfo().Alert
+	var events []notify.Event
+	newEvent := func(kind, name string, old, new fmt.Stringer, since time.Time, reason string) notify.Event {
+		// The alerts of a website that has not been checked before start with its first check
+		if since.IsZero() {
+			since = t
+		}
+		return notify.Event{
+			Time:         t,
+			Website:      url,
+			Alert:        kind,
+			Name:         name,
+			OldState:     old.String(),
+			NewState:     new.String(),
+			Availability: a.Availability,
+			Since:        since,
+			Reason:       reason,
+		}
+	}
+	if a.AlertState != before.availability {
+		reason := ""
+		if a.AlertState == alert.Unavailable {
+			reason = a.Reason
+		}
+		events = append(events, newEvent(notify.AvailabilityAlert, "", before.availability, a.AlertState, before.availabilitySince, reason))
+	}
+	if c := s.CertificateAlert; c.State != before.certificate {
+		events = append(events, newEvent(notify.CertificateAlert, "", before.certificate, c.State, before.certificateSince, c.Reason))
+	}
+	for _, tr := range s.SLOs {
+		// Objectives added by a reload have no previous state
+		old, ok := before.slos[tr]
+		if !ok || tr.Alert.State == old {
+			continue
+		}
+		events = append(events, newEvent(notify.SLOAlert, tr.SLO.Name, old, tr.Alert.State, before.slosSince[tr], tr.Alert.Reason))
+	}
+	return events
+}
@@ -0,0 +1,255 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+	"github.com/iwita/monitoring-website-stats/pkg/notify"
+)
+
+// Records the events it is notified of
+type recorder struct {
+	mutex  sync.Mutex
+	events []notify.Event
+}
+
+func (r *recorder) Notify(e notify.Event) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+// Waits up to a second until the recorder is notified of n events, and returns them
+func (r *recorder) wait(n int) []notify.Event {
+	for j := 0; j < 100; j++ {
+		r.mutex.Lock()
+		events := append([]notify.Event(nil), r.events...)
+		r.mutex.Unlock()
+		if len(events) >= n {
+			return events
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]notify.Event(nil), r.events...)
+}
+
+// Test that the transitions of the availability alert are delivered to the notifiers of each website
+func TestNotify(t *testing.T) {
+	var status int32 = http.StatusInternalServerError
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer ts.Close()
+
+	m := NewMonitor()
+	routed := newTestWebsite(ts.URL+"/routed", 10)
+	routed.Notify = []string{"oncall"}
+	all := newTestWebsite(ts.URL+"/all", 10)
+	m.Wbs = append(m.Wbs, routed, all)
+	oncall, log := &recorder{}, &recorder{}
+	if err := m.SetNotifiers(map[string]notify.Notifier{"oncall": oncall, "log": log}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetNotifiers(map[string]notify.Notifier{"log": log}); err == nil {
+		t.Errorf("expected an error for a website routed to an undefined notifier")
+	}
+
+	m.monitorOnce(m.ctx, routed)
+	m.monitorOnce(m.ctx, all)
+	if got, logged := oncall.wait(2), log.wait(1); len(got) != 2 || len(logged) != 1 {
+		t.Fatalf("got %d and %d events, expected 2 and 1", len(got), len(logged))
+	}
+	e := log.wait(1)[0]
+	if e.Website != all.Url || e.Alert != notify.AvailabilityAlert || e.OldState != "UP" || e.NewState != "DOWN" || e.Reason == "" {
+		t.Errorf("got %+v, expected %s to go down", e, all.Url)
+	}
+	if e.Resolved() {
+		t.Errorf("an UP to DOWN event is not a recovery")
+	}
+
+	// Checks without a transition are not notified, which the counts after Stop confirm
+	m.monitorOnce(m.ctx, all)
+
+	atomic.StoreInt32(&status, http.StatusOK)
+	// The availability is measured over time, which the waits above extended
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline) && m.StatsPerWebsite[all.Url].AlertInfo().Alert.AlertState != alert.Available; {
+		m.monitorOnce(m.ctx, all)
+		time.Sleep(time.Millisecond)
+	}
+	// Stop delivers the pending events
+	m.Stop()
+	logged := log.wait(0)
+	if len(logged) != 2 {
+		t.Fatalf("got %d events, expected the website to recover", len(logged))
+	}
+	e = logged[1]
+	if e.OldState != "DOWN" || e.NewState != "UP" || !e.Resolved() || e.Reason != "" {
+		t.Errorf("got %+v, expected a recovery", e)
+	}
+	if !e.Since.Equal(logged[0].Time) {
+		t.Errorf("got since %v, expected the start of the incident %v", e.Since, logged[0].Time)
+	}
+	// The website routed to every notifier also recovered on oncall
+	if got := oncall.wait(0); len(got) != 3 {
+		t.Errorf("got %d events, expected 3", len(got))
+	}
+}
+
+// Blocks on every event until it is released
+type blockingNotifier struct {
+	recorder
+	release chan struct{}
+}
+
+func (b *blockingNotifier) Notify(e notify.Event) error {
+	<-b.release
+	return b.recorder.Notify(e)
+}
+
+// Test that a notifier blocking longer than the interval delays neither the checks nor the other notifiers
+func TestSlowNotifier(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	m := NewMonitor()
+	wb := newTestWebsite(ts.URL, 20)
+	m.Wbs = append(m.Wbs, wb)
+	slow, log := &blockingNotifier{release: make(chan struct{})}, &recorder{}
+	if err := m.SetNotifiers(map[string]notify.Notifier{"blocked": slow, "log": log}); err != nil {
+		t.Fatal(err)
+	}
+	go m.Exec()
+	if got := log.wait(1); len(got) != 1 {
+		t.Fatalf("got %d events, expected the website to go down", len(got))
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	m.mutex.Lock()
+	res := m.StatsPerWebsite[wb.Url].OverallInfo.GetResult()
+	m.mutex.Unlock()
+	if res.Responses < 5 {
+		t.Errorf("got %d checks while the notifier was blocked, expected them to continue", res.Responses)
+	}
+	if res.Coverage < 90 {
+		t.Errorf("got a coverage of %v%%, expected no gaps while the notifier was blocked", res.Coverage)
+	}
+
+	// The event is still delivered once the notifier is released
+	close(slow.release)
+	m.Stop()
+	if got := slow.wait(0); len(got) != 1 {
+		t.Errorf("got %d events, expected the pending one to be delivered", len(got))
+	}
+}
+
+// Records the digests it receives, due at a fixed time
+type digestRecorder struct {
+	recorder
+	due     time.Time
+	digests []notify.Digest
+}
+
+func (r *digestRecorder) NextDigest(after time.Time) time.Time {
+	if after.Before(r.due) {
+		return r.due
+	}
+	return r.due.Add(DigestPeriod)
+}
+
+func (r *digestRecorder) SendDigest(d notify.Digest) error {
+	r.digests = append(r.digests, d)
+	return nil
+}
+
+// Test that the digests summarize the websites routed to each notifier, once they are due
+func TestDigest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	m := NewMonitor()
+	up := newTestWebsite(ts.URL+"/up", 10)
+	up.Notify = []string{"daily"}
+	down := newTestWebsite(ts.URL+"/down", 10)
+	down.Notify = []string{"oncall"}
+	m.Wbs = append(m.Wbs, up, down)
+	due := time.Now().Add(time.Hour)
+	daily, oncall := &digestRecorder{due: due}, &recorder{}
+	if err := m.SetNotifiers(map[string]notify.Notifier{"daily": daily, "oncall": oncall}); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+	for j := 0; j < 3; j++ {
+		m.monitorOnce(m.ctx, up)
+		m.monitorOnce(m.ctx, down)
+	}
+
+	m.sendDigests(due.Add(-time.Minute))
+	if len(daily.digests) != 0 {
+		t.Fatalf("got %d digests before they are due", len(daily.digests))
+	}
+	m.sendDigests(due)
+	m.sendDigests(due.Add(time.Minute))
+	if len(daily.digests) != 1 {
+		t.Fatalf("got %d digests, expected 1", len(daily.digests))
+	}
+	d := daily.digests[0]
+	if !d.To.Equal(due) || d.To.Sub(d.From) != DigestPeriod {
+		t.Errorf("got a digest from %v to %v", d.From, d.To)
+	}
+	if len(d.Websites) != 1 || d.Websites[0].Website != up.Url {
+		t.Fatalf("got %+v, expected only %s", d.Websites, up.Url)
+	}
+	if w := d.Websites[0]; w.Checks != 3 || w.Availability != 1 || w.P90 <= 0 || len(w.Incidents) != 0 {
+		t.Errorf("got %+v, expected 3 successful checks", w)
+	}
+
+	// The incidents of the website that went down
+	d = m.Digest([]string{down.Url, "http://unknown"}, due.Add(-DigestPeriod), due)
+	if w := d.Websites[0]; w.Checks != 3 || w.Availability != 0 || len(w.Incidents) != 1 || w.Stats.Incidents != 1 || !w.Incidents[0].Ongoing() {
+		t.Errorf("got %+v, expected an ongoing incident", w)
+	}
+	if w := d.Websites[0]; w.Stats.MTBF >= time.Minute {
+		t.Errorf("got MTBF %v, expected only the time since monitoring started", w.Stats.MTBF)
+	}
+	if w := d.Websites[1]; w.Checks != 0 || len(w.Incidents) != 0 {
+		t.Errorf("got %+v for a website without checks", w)
+	}
+}
+
+// Test that a reload keeps the digests that are due, of the notifiers that did not change
+func TestDigestReload(t *testing.T) {
+	m := NewMonitor()
+	defer m.Stop()
+	due := time.Now().Add(-time.Minute)
+	if err := m.SetNotifiers(map[string]notify.Notifier{"daily": &digestRecorder{due: due}, "changed": &digestRecorder{due: due}}); err != nil {
+		t.Fatal(err)
+	}
+	// Both digests became due after the last check
+	m.digestDue["daily"], m.digestDue["changed"] = due, due
+
+	daily, changed := &digestRecorder{due: due}, &digestRecorder{due: due.Add(time.Second)}
+	if err := m.SetNotifiers(map[string]notify.Notifier{"daily": daily, "changed": changed}); err != nil {
+		t.Fatal(err)
+	}
+	m.sendDigests(time.Now())
+	if len(daily.digests) != 1 {
+		t.Errorf("got %d digests after the reload, expected 1", len(daily.digests))
+	}
+	if len(changed.digests) != 0 {
+		t.Errorf("got %d digests of a changed notifier, expected it to be scheduled anew", len(changed.digests))
+	}
+}
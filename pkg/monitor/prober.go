@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// The supported probe types
+const (
+	HTTPProbe = "http"
+	TCPProbe  = "tcp"
+	DNSProbe  = "dns"
+	TLSProbe  = "tls"
+)
+
+// Prober performs a single check of a website.
+// The context carries the timeout of the check, and the returned
+// response is added to the statistics like any http response
+type Prober interface {
+	Probe(ctx context.Context) *info.Response
+}
+
+// Creates the prober for the type of the website
+func NewProber(wb Website) (Prober, error) {
+	switch wb.Type {
+	case "", HTTPProbe:
+		return &httpProber{wb: wb}, nil
+	case TCPProbe:
+		if _, _, err := net.SplitHostPort(wb.Url); err != nil {
+			return nil, fmt.Errorf("tcp probe requires an address (host:port): %v", err)
+		}
+		return &tcpProber{wb: wb}, nil
+	case DNSProbe:
+		if wb.Url == "" {
+			return nil, fmt.Errorf("dns probe requires a hostname")
+		}
+		return &dnsProber{wb: wb}, nil
+	case TLSProbe:
+		if _, _, err := net.SplitHostPort(wb.Url); err != nil {
+			return nil, fmt.Errorf("tls probe requires an address (host:port): %v", err)
+		}
+		return &tlsProber{wb: wb}, nil
+	}
+	return nil, fmt.Errorf("unknown probe type %q", wb.Type)
+}
+
+// Resolves the host of the address, unless it is an ip, and connects to it.
+// The phases of the connection are recorded by the tracer, when given
+func dial(ctx context.Context, address string, t *tracer) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs := []string{host}
+	if net.ParseIP(host) == nil {
+		if t != nil {
+			t.set(&t.dnsStart)
+		}
+		addrs, err = net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			t.set(&t.dnsDone)
+		}
+	}
+	if t != nil {
+		t.set(&t.connectStart)
+	}
+	conn, err := dialAny(ctx, addrs, port)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil {
+		t.set(&t.connectDone)
+	}
+	return conn, nil
+}
+
+// Connects to the first of the addresses that accepts the connection,
+// returning the error of the last one when none does
+func dialAny(ctx context.Context, addrs []string, port string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	var err error
+	for _, addr := range addrs {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+// Completes a sample of a probe that does not receive an http response
+func finishSample(sample *info.Response, t *tracer, err error) *info.Response {
+	end := time.Now()
+	if err != nil {
+		sample.Failure = classifyError(err, t)
+		sample.Reason = err.Error()
+	} else {
+		sample.Timing = t.timing(end)
+		sample.Delay = sample.Timing.Total
+	}
+	sample.Success = sample.Reason == ""
+	return sample
+}
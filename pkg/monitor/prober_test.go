@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// Runs the prober of the website once
+func probeOnce(t *testing.T, wb Website) *info.Response {
+	p, err := NewProber(wb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), wb.timeout())
+	defer cancel()
+	return p.Probe(ctx)
+}
+
+// Test the tcp, tls and dns probers against local endpoints
+func TestProbers(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	address := strings.TrimPrefix(ts.URL, "https://")
+
+	res := probeOnce(t, Website{Type: TCPProbe, Url: address})
+	if !res.Success || res.Timing == nil || res.Timing.Connect <= 0 {
+		t.Errorf("tcp: got success %v (%s)", res.Success, res.Reason)
+	}
+
+	res = probeOnce(t, Website{Type: TLSProbe, Url: address})
+	if res.Success || res.Failure != info.TLSError || res.Certificate == nil {
+		t.Errorf("tls: a self-signed certificate should fail, got %q", res.Failure)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := l.Addr().String()
+	l.Close()
+	res = probeOnce(t, Website{Type: TCPProbe, Url: closed})
+	if res.Failure != info.ConnectionRefused {
+		t.Errorf("tcp: got %q, want %q", res.Failure, info.ConnectionRefused)
+	}
+
+	res = probeOnce(t, Website{Type: DNSProbe, Url: "localhost", Assertions: &Assertions{BodyContains: []string{"127.0.0.1"}}})
+	if !res.Success {
+		t.Errorf("dns: got %q", res.Reason)
+	}
+
+	if _, err := NewProber(Website{Type: "icmp", Url: "localhost"}); err == nil {
+		t.Errorf("expected an error for an unknown probe type")
+	}
+	if _, err := NewProber(Website{Type: TCPProbe, Url: "localhost"}); err == nil {
+		t.Errorf("expected an error for a tcp address without a port")
+	}
+}
+
+// Test that the addresses of a host are tried in turn, until one accepts the connection
+func TestDialAny(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	// Nothing listens on 127.0.0.2, the connection is refused
+	conn, err := dialAny(context.Background(), []string{"127.0.0.2", "127.0.0.1"}, port)
+	if err != nil {
+		t.Fatalf("got %v, expected the second address to be connected", err)
+	}
+	conn.Close()
+	if _, err := dialAny(context.Background(), []string{"127.0.0.2"}, port); err == nil {
+		t.Errorf("expected an error when no address accepts the connection")
+	}
+}
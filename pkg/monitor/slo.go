@@ -0,0 +1,219 @@
+package monitor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+	"github.com/iwita/monitoring-website-stats/pkg/sketch"
+)
+
+// SLO is a service level objective of a website, e.g. 99.9% of the checks
+// successful over 30 days, or 95% of the checks successful and under 300ms
+type SLO struct {
+	Name string
+	// The ratio of checks that must meet the objective, e.g. 0.999
+	Objective float64
+	// The period the error budget is calculated over
+	Period time.Duration
+	// The response time a successful check must not exceed, when zero every successful check is good
+	Latency time.Duration
+	// The rules of the alert, by default DefaultBurnRates
+	BurnRates []BurnRate
+}
+
+// BurnRate is a multi-window alerting rule: it fires when the error budget is
+// consumed at least Factor times faster than allowed, over both the long and the short window
+type BurnRate struct {
+	Long   time.Duration `json:"long"`
+	Short  time.Duration `json:"short"`
+	Factor float64       `json:"factor"`
+}
+
+// The burn-rate rules, when none are configured: 2% of a 30 days budget
+// consumed within an hour, or 5% within 6 hours
+var DefaultBurnRates = []BurnRate{
+	{Long: time.Hour, Short: 5 * time.Minute, Factor: 14.4},
+	{Long: 6 * time.Hour, Short: 30 * time.Minute, Factor: 6},
+}
+
+// The period of an objective, when none is configured
+const DefaultSLOPeriod = 30 * 24 * time.Hour
+
+// The number of responses the short window of a rule needs before the rule can fire,
+// which must also have been observed in full. Otherwise the first failures of a website
+// would fill windows that barely started, and every rule would fire at once
+const MinBurnRateSamples = 10
+
+// Checks that the objective, its period and its rules are valid
+func (s SLO) Validate() error {
+	if s.Objective <= 0 || s.Objective >= 1 {
+		return fmt.Errorf("slo %q: invalid objective %v, must be between 0 and 1", s.Name, s.Objective)
+	}
+	if s.Period <= 0 {
+		return fmt.Errorf("slo %q: period must be positive", s.Name)
+	}
+	if s.Latency < 0 {
+		return fmt.Errorf("slo %q: latency must not be negative", s.Name)
+	}
+	for _, r := range s.BurnRates {
+		if r.Short <= 0 || r.Long <= r.Short {
+			return fmt.Errorf("slo %q: burn rate windows %v/%v, the long window must be longer than the short one", s.Name, r.Long, r.Short)
+		}
+		if r.Factor <= 0 {
+			return fmt.Errorf("slo %q: burn rate factor %v must be positive", s.Name, r.Factor)
+		}
+	}
+	return nil
+}
+
+// Returns the configured rules, or the default ones
+func (s SLO) burnRates() []BurnRate {
+	if len(s.BurnRates) == 0 {
+		return DefaultBurnRates
+	}
+	return s.BurnRates
+}
+
+// SLOResult is the state of an objective, as calculated from its windows
+type SLOResult struct {
+	Name      string        `json:"name"`
+	Objective float64       `json:"objective"`
+	Period    time.Duration `json:"period"`
+	Latency   time.Duration `json:"latency,omitempty"`
+	// The ratio of checks of the period that met the objective
+	SLI float64 `json:"sli"`
+	// The ratio of the error budget of the period that is left, negative once exceeded
+	ErrorBudget float64          `json:"errorBudget"`
+	BurnRates   []BurnRateResult `json:"burnRates"`
+	State       string           `json:"state"`
+	Reason      string           `json:"reason,omitempty"`
+}
+
+// BurnRateResult is the rate the error budget is consumed at over the windows of a rule
+type BurnRateResult struct {
+	BurnRate
+	LongRate  float64 `json:"longRate"`
+	ShortRate float64 `json:"shortRate"`
+	Firing    bool    `json:"firing"`
+}
+
+// SLOTracker keeps the windows an objective is evaluated on, and its alert
+type SLOTracker struct {
+	SLO   SLO
+	Alert *alert.SLOAlert
+	// One window per distinct duration of the period and the rules, stored in sketches
+	windows map[time.Duration]*info.Info
+	// The time of the most recent check
+	last time.Time
+}
+
+// Creates the windows of an objective, that has been validated
+func newSLOTracker(slo SLO, interval time.Duration) *SLOTracker {
+	t := &SLOTracker{
+		SLO:     slo,
+		Alert:   alert.NewSLOAlert(slo.Name),
+		windows: make(map[time.Duration]*info.Info),
+	}
+	durations := []time.Duration{slo.Period}
+	for _, r := range slo.burnRates() {
+		durations = append(durations, r.Long, r.Short)
+	}
+	for _, d := range durations {
+		if _, ok := t.windows[d]; ok {
+			continue
+		}
+		w := info.NewInfo(d, interval, false)
+		w.UseSketch(sketch.DefaultAccuracy)
+		w.SetLatencyObjective(slo.Latency)
+		t.windows[d] = w
+	}
+	return t
+}
+
+// Adds a response to every window of the objective and updates its alert.
+// It returns true when the state of the alert changed
+func (t *SLOTracker) update(res *info.Response) bool {
+	for _, w := range t.windows {
+		w.Update(res)
+	}
+	t.last = res.Time
+	r := t.Result()
+	// The transitions are timed by the checks, as those of the availability alert
+	return t.Alert.Update(res.Time, r.Reason != "", r.Reason)
+}
+
+// Reports whether the short window of a rule has enough responses, and has been
+// observed for its whole duration, for the rule to fire
+func (t *SLOTracker) observed(r BurnRate) bool {
+	w := t.windows[r.Short]
+	return w.TotalResponses >= MinBurnRateSamples && !t.last.Before(w.Since().Add(r.Short))
+}
+
+// Returns the burn rate of a window, i.e. its error ratio relative to the error budget
+func (t *SLOTracker) burnRate(d time.Duration) float64 {
+	ratio, _ := t.windows[d].ErrorRatio()
+	return ratio / (1 - t.SLO.Objective)
+}
+
+// Calculates the error budget and the burn rates of the objective
+func (t *SLOTracker) Result() *SLOResult {
+	s := t.SLO
+	res := &SLOResult{
+		Name:      s.Name,
+		Objective: s.Objective,
+		Period:    s.Period,
+		Latency:   s.Latency,
+		SLI:       1,
+		State:     t.Alert.State.String(),
+	}
+	if ratio, ok := t.windows[s.Period].ErrorRatio(); ok {
+		res.SLI = 1 - ratio
+	}
+	res.ErrorBudget = 1 - (1-res.SLI)/(1-s.Objective)
+	var firing []string
+	for _, r := range s.burnRates() {
+		br := BurnRateResult{
+			BurnRate:  r,
+			LongRate:  t.burnRate(r.Long),
+			ShortRate: t.burnRate(r.Short),
+		}
+		br.Firing = br.LongRate >= r.Factor && br.ShortRate >= r.Factor && t.observed(r)
+		if br.Firing {
+			firing = append(firing, fmt.Sprintf("burn rate %.1fx over %v and %.1fx over %v, above %vx",
+				br.LongRate, r.Long, br.ShortRate, r.Short, r.Factor))
+		}
+		res.BurnRates = append(res.BurnRates, br)
+	}
+	res.Reason = strings.Join(firing, "; ")
+	return res
+}
+
+// Creates the trackers of the objectives of a website, keeping those that did not change
+func (s *Statistics) setSLOs(slos []SLO, interval time.Duration) {
+	trackers := make([]*SLOTracker, 0, len(slos))
+	for _, slo := range slos {
+		var tracker *SLOTracker
+		for _, t := range s.SLOs {
+			if reflect.DeepEqual(t.SLO, slo) {
+				tracker = t
+				break
+			}
+		}
+		if tracker == nil {
+			tracker = newSLOTracker(slo, interval)
+		}
+		trackers = append(trackers, tracker)
+	}
+	s.SLOs = trackers
+}
+
+// Adapts the windows of the objective to a new interval of the website
+func (t *SLOTracker) resize(interval time.Duration) {
+	for _, w := range t.windows {
+		w.Resize(interval)
+	}
+}
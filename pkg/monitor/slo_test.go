@@ -0,0 +1,111 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// Adds a response every 10 seconds for the given duration, returning the time after the last one
+func feedSLO(t *SLOTracker, start time.Time, d time.Duration, delay time.Duration, success bool) time.Time {
+	for ; d > 0; d -= 10 * time.Second {
+		t.update(&info.Response{Time: start, Delay: delay, Success: success})
+		start = start.Add(10 * time.Second)
+	}
+	return start
+}
+
+// Test that the burn-rate alert fires and resolves with the failures of the windows of its rules
+func TestSLOBurnRate(t *testing.T) {
+	slo := SLO{Name: "availability", Objective: 0.99, Period: DefaultSLOPeriod}
+	if err := slo.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tracker := newSLOTracker(slo, 10*time.Second)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	now = feedSLO(tracker, now, 2*time.Hour, 10*time.Millisecond, true)
+	if r := tracker.Result(); r.SLI != 1 || r.ErrorBudget != 1 || tracker.Alert.State != alert.SLOOK {
+		t.Errorf("got sli %v, budget %v, state %v, want 1, 1, OK", r.SLI, r.ErrorBudget, tracker.Alert.State)
+	}
+
+	// 10 minutes of failures burn the budget faster than both rules allow
+	now = feedSLO(tracker, now, 10*time.Minute, 10*time.Millisecond, false)
+	r := tracker.Result()
+	if tracker.Alert.State != alert.SLOBurning {
+		t.Errorf("got state %v after the failures, want BURNING", tracker.Alert.State)
+	}
+	for _, br := range r.BurnRates {
+		if !br.Firing {
+			t.Errorf("rule %v/%v: got %.1fx and %.1fx, want both above %vx", br.Long, br.Short, br.LongRate, br.ShortRate, br.Factor)
+		}
+	}
+	// 60 of the 780 checks failed, with 1% of them allowed to
+	if want := 1 - (60.0/780)/0.01; r.ErrorBudget < want-1e-9 || r.ErrorBudget > want+1e-9 {
+		t.Errorf("got budget %v, want %v", r.ErrorBudget, want)
+	}
+
+	// The short windows recover first, the alert resolves once no rule fires
+	now = feedSLO(tracker, now, 10*time.Minute, 10*time.Millisecond, true)
+	if tracker.Alert.State != alert.SLOBurning {
+		t.Errorf("got state %v, want BURNING while the 6h rule fires", tracker.Alert.State)
+	}
+	feedSLO(tracker, now, time.Hour, 10*time.Millisecond, true)
+	if tracker.Alert.State != alert.SLOOK {
+		t.Errorf("got state %v after the recovery, want OK", tracker.Alert.State)
+	}
+	if len(tracker.Alert.Transitions) != 2 {
+		t.Errorf("got %d transitions, want 2", len(tracker.Alert.Transitions))
+	}
+}
+
+// Test that slow successful responses do not meet a latency objective
+func TestSLOLatency(t *testing.T) {
+	tracker := newSLOTracker(SLO{Name: "latency", Objective: 0.95, Period: time.Hour, Latency: 300 * time.Millisecond}, 10*time.Second)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = feedSLO(tracker, now, 150*time.Second, 100*time.Millisecond, true)
+	feedSLO(tracker, now, 50*time.Second, 500*time.Millisecond, true)
+	if r := tracker.Result(); r.SLI != 0.75 {
+		t.Errorf("got sli %v, want 0.75", r.SLI)
+	}
+}
+
+func TestSLOValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		slo  SLO
+	}{
+		{"objective of 100%", SLO{Objective: 1, Period: time.Hour}},
+		{"no period", SLO{Objective: 0.99}},
+		{"negative latency", SLO{Objective: 0.99, Period: time.Hour, Latency: -time.Second}},
+		{"short window longer than the long one", SLO{Objective: 0.99, Period: time.Hour,
+			BurnRates: []BurnRate{{Long: time.Minute, Short: time.Hour, Factor: 2}}}},
+		{"no factor", SLO{Objective: 0.99, Period: time.Hour,
+			BurnRates: []BurnRate{{Long: time.Hour, Short: time.Minute}}}},
+	}
+	for _, tt := range tests {
+		if err := tt.slo.Validate(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+// Test that a website failing from the start fires once the short window has been observed in full,
+// at the time of the check
+func TestSLOMinimumCoverage(t *testing.T) {
+	tracker := newSLOTracker(SLO{Name: "availability", Objective: 0.99, Period: DefaultSLOPeriod}, 10*time.Second)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := feedSLO(tracker, start, 5*time.Minute, 0, false)
+	if tracker.Alert.State != alert.SLOOK {
+		t.Errorf("got state %v before the 5m window was observed, want OK", tracker.Alert.State)
+	}
+	feedSLO(tracker, now, 10*time.Second, 0, false)
+	if tracker.Alert.State != alert.SLOBurning {
+		t.Fatalf("got state %v, want BURNING", tracker.Alert.State)
+	}
+	if tr := tracker.Alert.Transitions[0]; !tr.Time.Equal(now) {
+		t.Errorf("got a transition at %v, want %v", tr.Time, now)
+	}
+}
@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// Snapshot is a copy of the statistics of every website at a point in time.
+// The monitor does not modify it afterwards, so it can be read without locking
+type Snapshot struct {
+	Time time.Time
+	// The windows of the monitor, in the order of the results of every website
+	Windows  []Window
+	Websites []WebsiteSnapshot
+}
+
+// WebsiteSnapshot is a copy of the statistics of a single website
+type WebsiteSnapshot struct {
+	Url string
+	// Whether a check of the website has completed. Until then, only the url is set
+	Ready bool
+	// The result of every window, nil for the windows without responses
+	Results []*info.Result
+	// The result since monitoring started, and when it started
+	Overall *info.Result
+	Since   time.Time
+	// The trend selected by SetTrend, nil when none is
+	Trend *info.Trend
+	// The availability alert, evaluated on the alerting window
+	Alert *alert.Alert
+	// The most recently inspected certificate and its alert, nil for plain http
+	Certificate      *info.Certificate
+	CertificateAlert *alert.CertificateAlert
+	// The redirects followed by the most recent request
+	Redirects []info.Redirect
+	SLOs      []SLOSnapshot
+	// The days of the history of the last HistoryDays days, without the days without checks
+	Days []*info.Bucket
+}
+
+// The number of days of history included in the snapshots
+const HistoryDays = 30
+
+// SLOSnapshot is a copy of the state of an objective of a website
+type SLOSnapshot struct {
+	Result *SLOResult
+	Alert  *alert.SLOAlert
+}
+
+// trend selects the windows compared by the snapshots
+type trend struct {
+	window, baseline string
+	threshold        float64
+}
+
+// SetTrend makes the snapshots compare the window against the baseline window,
+// with changes below the threshold (e.g. 0.1) considered stable
+func (m *Monitor) SetTrend(window, baseline string, threshold float64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.WindowIndex(window) < 0 {
+		return fmt.Errorf("trend window %q is not defined", window)
+	}
+	if m.WindowIndex(baseline) < 0 {
+		return fmt.Errorf("trend baseline %q is not defined", baseline)
+	}
+	m.trend = &trend{window: window, baseline: baseline, threshold: threshold}
+	return nil
+}
+
+// Snapshot copies the statistics of every website. The results of a window are
+// recalculated when its refresh interval has passed, and reused otherwise
+func (m *Monitor) Snapshot() *Snapshot {
+	return m.snapshot(false)
+}
+
+// SnapshotNow copies the statistics of every website as Snapshot does,
+// recalculating the results of every window
+func (m *Monitor) SnapshotNow() *Snapshot {
+	return m.snapshot(true)
+}
+
+func (m *Monitor) snapshot(force bool) *Snapshot {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	s := &Snapshot{
+		Time:     time.Now(),
+		Windows:  append([]Window(nil), m.Windows...),
+		Websites: make([]WebsiteSnapshot, 0, len(m.Wbs)),
+	}
+	for _, wb := range m.Wbs {
+		s.Websites = append(s.Websites, m.websiteSnapshot(wb.Url, s.Time, force))
+	}
+	return s
+}
+
+// WebsiteSnapshot copies the statistics of a single website, as Snapshot does.
+// It returns false when the website is not monitored
+func (m *Monitor) WebsiteSnapshot(url string) (WebsiteSnapshot, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, wb := range m.Wbs {
+		if wb.Url == url {
+			return m.websiteSnapshot(url, time.Now(), false), true
+		}
+	}
+	return WebsiteSnapshot{}, false
+}
+
+// Copies the statistics of a website. The mutex must be held
+func (m *Monitor) websiteSnapshot(url string, now time.Time, force bool) WebsiteSnapshot {
+	ws := WebsiteSnapshot{Url: url}
+	stats, ok := m.StatsPerWebsite[url]
+	if !ok {
+		return ws
+	}
+	ws.Ready = true
+	ws.Results = stats.results(m.Windows, now, force)
+	ws.Overall = stats.OverallInfo.GetResult()
+	ws.Since = stats.OverallInfo.Since()
+	if t := m.trend; t != nil {
+		tr := info.CompareTrend(stats.Window(t.window), stats.Window(t.baseline), t.threshold)
+		ws.Trend = &tr
+	}
+	ws.Alert = stats.AlertInfo().Alert.Clone()
+	if stats.Certificate != nil {
+		c := *stats.Certificate
+		ws.Certificate = &c
+		ws.CertificateAlert = stats.CertificateAlert.Clone()
+	}
+	ws.Redirects = append([]info.Redirect(nil), stats.Redirects...)
+	for _, slo := range stats.SLOs {
+		ws.SLOs = append(ws.SLOs, SLOSnapshot{Result: slo.Result(), Alert: slo.Alert.Clone()})
+	}
+	// The buckets are copies, and a day is one of the widths of the history
+	ws.Days, _ = stats.History.Query(24*time.Hour, now.Add(-HistoryDays*24*time.Hour), now)
+	return ws
+}
+
+// History returns the buckets of the given width of the history of a website that overlap
+// the period from..to, e.g. the days of the last 30 days. The buckets are copies,
+// that are not affected by later checks
+func (m *Monitor) History(url string, width time.Duration, from, to time.Time) ([]*info.Bucket, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if stats, ok := m.StatsPerWebsite[url]; ok {
+		return stats.History.Query(width, from, to)
+	}
+	for _, wb := range m.Wbs {
+		if wb.Url == url {
+			// Not checked yet
+			return []*info.Bucket{}, nil
+		}
+	}
+	return nil, fmt.Errorf("website %s is not monitored", url)
+}
+
+// Returns the results of the windows, recalculating those whose refresh interval has passed.
+// The results are shared by the snapshots, and never modified
+func (s *Statistics) results(windows []Window, now time.Time, force bool) []*info.Result {
+	if len(s.cached) != len(s.Windows) {
+		s.cached = make([]*info.Result, len(s.Windows))
+		s.refreshed = make([]time.Time, len(s.Windows))
+	}
+	for j, w := range windows {
+		if force || s.cached[j] == nil || now.Sub(s.refreshed[j]) >= w.Refresh {
+			s.cached[j] = s.Windows[j].GetResult()
+			s.refreshed[j] = now
+		}
+	}
+	return append([]*info.Result(nil), s.cached...)
+}
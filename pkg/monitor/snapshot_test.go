@@ -0,0 +1,118 @@
+package monitor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test that snapshots can be taken while the websites are monitored
+func TestSnapshot(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	m := NewMonitor()
+	windows := []Window{{Name: "short", Duration: time.Minute}, {Name: "long", Duration: time.Hour, Refresh: time.Hour}}
+	if err := m.SetWindows(windows, "short"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetTrend("short", "long", 0.1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetTrend("short", "missing", 0.1); err == nil {
+		t.Errorf("expected an error for an undefined trend window")
+	}
+	m.Wbs = append(m.Wbs, newTestWebsite(ts.URL, 10))
+
+	// Before the first check only the url is known
+	if s := m.Snapshot(); len(s.Websites) != 1 || s.Websites[0].Ready || s.Websites[0].Url != ts.URL {
+		t.Fatalf("got %+v before the first check", s.Websites)
+	}
+
+	go m.Exec()
+	defer m.Stop()
+	deadline := time.Now().Add(2 * time.Second)
+	var first *Snapshot
+	for time.Now().Before(deadline) {
+		// Read everything a renderer reads, while the checks update the statistics
+		s := m.Snapshot()
+		if ws := s.Websites[0]; ws.Ready && ws.Results[1] != nil {
+			if first == nil {
+				first = s
+			}
+			_ = ws.Alert.PrintTest()
+			_ = ws.Trend.Direction
+			if ws.Overall.Responses < ws.Results[0].Responses {
+				t.Errorf("got %d responses since the start, fewer than the %d of the window", ws.Overall.Responses, ws.Results[0].Responses)
+			}
+		}
+		if first != nil && s.Websites[0].Results[0].Responses > first.Websites[0].Results[0].Responses+2 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if first == nil {
+		t.Fatalf("no results within 2s")
+	}
+
+	s := m.Snapshot()
+	// The long window is only recalculated once its refresh interval has passed
+	if s.Websites[0].Results[1] != first.Websites[0].Results[1] {
+		t.Errorf("the result of the long window should be reused")
+	}
+	if s.Websites[0].Results[0] == first.Websites[0].Results[0] {
+		t.Errorf("the result of the short window should be recalculated")
+	}
+	if now := m.SnapshotNow(); now.Websites[0].Results[1] == first.Websites[0].Results[1] {
+		t.Errorf("SnapshotNow should recalculate every window")
+	}
+
+	ws, ok := m.WebsiteSnapshot(ts.URL)
+	if !ok || !ws.Ready {
+		t.Errorf("got %v, %v for a monitored website", ws.Ready, ok)
+	}
+	if _, ok := m.WebsiteSnapshot("http://unknown"); ok {
+		t.Errorf("expected no snapshot of a website that is not monitored")
+	}
+}
+
+// Test that the history can be queried while the websites are monitored
+func TestHistory(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	m := NewMonitor()
+	m.Wbs = append(m.Wbs, newTestWebsite(ts.URL, 10))
+	from := time.Now().Add(-time.Hour)
+	if b, err := m.History(ts.URL, time.Minute, from, time.Now()); err != nil || len(b) != 0 {
+		t.Errorf("got %v and %v before the first check, want no buckets", b, err)
+	}
+	if _, err := m.History("http://unknown", time.Minute, from, time.Now()); err == nil {
+		t.Errorf("expected an error for a website that is not monitored")
+	}
+
+	go m.Exec()
+	defer m.Stop()
+	var checks int
+	for deadline := time.Now().Add(2 * time.Second); checks < 20 && time.Now().Before(deadline); {
+		buckets, err := m.History(ts.URL, time.Minute, from, time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checks = 0
+		for _, b := range buckets {
+			checks += b.Count
+		}
+		if days := m.Snapshot().Websites[0].Days; checks > 0 && (len(days) == 0 || days[len(days)-1].Count == 0) {
+			t.Errorf("got %d checks in the history, but none in the days of the snapshot", checks)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if checks < 20 {
+		t.Errorf("got %d checks in the history within 2s", checks)
+	}
+	if _, err := m.History(ts.URL, time.Second, from, time.Now()); err == nil {
+		t.Errorf("expected an error for a width without buckets")
+	}
+}
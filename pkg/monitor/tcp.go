@@ -0,0 +1,22 @@
+package monitor
+
+import (
+	"context"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// tcpProber checks that a connection to the address can be established
+type tcpProber struct {
+	wb Website
+}
+
+func (p *tcpProber) Probe(ctx context.Context) *info.Response {
+	t := &tracer{start: time.Now()}
+	conn, err := dial(ctx, p.wb.Url, t)
+	if err == nil {
+		conn.Close()
+	}
+	return finishSample(&info.Response{}, t, err)
+}
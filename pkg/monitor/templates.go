@@ -1,18 +1,32 @@
 package monitor
 
+// The output of a website consists of the header, one section per window and the footer
 const (
-	OutputTemplate = `%s
+	HeaderTemplate = `%s
 **********************************************************************************************************
 %s 
 *-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-
-                [Max / Avg / 90th percentile] response time		|	Availability
+                [Max / Avg / percentiles] response time		|	Availability
 *-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-*-
-Past 10 minutes	|[%v/%v/%v] (%s)			|	%v%%	
-%s
+`
+
+	WindowTemplate = `Past %-10s|[%v/%v/%v]%s			|	%v%%%s
+%s%s
 ----------------------------------------------------------------------------------------------------------
-Past Hour       |[%v/%v/%v]	    				|	%v%%
-%s
-**********************************************************************************************************
+`
+
+	// Used instead of the WindowTemplate, before the first response of the window
+	EmptyWindowTemplate = `Past %-10s|Metrics currently unavailable
+----------------------------------------------------------------------------------------------------------
+`
+
+	// The statistics since monitoring started, before the footer
+	OverallTemplate = `Since start|[%v/%v/%s]			|	%v%%
+started %v, %d checks
+%s----------------------------------------------------------------------------------------------------------
+`
+
+	FooterTemplate = `**********************************************************************************************************
 ----------------------------------------------------------------------------------------------------------
 `
 )
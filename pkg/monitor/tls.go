@@ -0,0 +1,30 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// tlsProber completes a TLS handshake with the address and inspects its certificate,
+// e.g. for SMTP or database endpoints that are not http websites
+type tlsProber struct {
+	wb Website
+}
+
+func (p *tlsProber) Probe(ctx context.Context) *info.Response {
+	sample := &info.Response{}
+	t := &tracer{start: time.Now()}
+	cs, err := handshake(ctx, p.wb.Url, t)
+	if err == nil {
+		host, _, _ := net.SplitHostPort(p.wb.Url)
+		sample.Certificate = verifyChain(cs.PeerCertificates, host, time.Now())
+		if c := sample.Certificate; c != nil && (!c.Trusted || !c.HostnameMatch) {
+			sample.Failure = info.TLSError
+			sample.Reason = c.Error
+		}
+	}
+	return finishSample(sample, t, err)
+}
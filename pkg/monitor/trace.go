@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+)
+
+// tracer records the time of every phase of a single request.
+// The hooks of the trace may be called from different goroutines,
+// so the recorded times are protected by a mutex
+type tracer struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+func (t *tracer) set(field *time.Time) {
+	now := time.Now()
+	t.mutex.Lock()
+	// Keep the first occurence, e.g. when dialing more than one address
+	if field.IsZero() {
+		*field = now
+	}
+	t.mutex.Unlock()
+}
+
+func (t *tracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(ci httptrace.GotConnInfo) {
+			t.mutex.Lock()
+			t.reused = ci.Reused
+			t.mutex.Unlock()
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { t.set(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone) },
+		ConnectStart:         func(network, addr string) { t.set(&t.connectStart) },
+		ConnectDone:          func(network, addr string, err error) { t.set(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.set(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte) },
+	}
+}
+
+// Time to the first byte of the response since the given time,
+// e.g. the start of the first request in a chain of redirects
+func (t *tracer) firstByteSince(start time.Time) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.firstByte.IsZero() {
+		return 0
+	}
+	return t.firstByte.Sub(start)
+}
+
+// Returns the duration of every phase, given the time the body was completely read
+func (t *tracer) timing(end time.Time) *info.Timing {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return &info.Timing{
+		DNS:      between(t.dnsStart, t.dnsDone),
+		Connect:  between(t.connectStart, t.connectDone),
+		TLS:      between(t.tlsStart, t.tlsDone),
+		Server:   between(t.wroteRequest, t.firstByte),
+		Transfer: between(t.firstByte, end),
+		Total:    between(t.start, end),
+		Reused:   t.reused,
+	}
+}
+
+// Returns the phase that was still in progress, when the request timed out
+func (t *tracer) timedOutPhase() info.FailureClass {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	switch {
+	case !t.dnsStart.IsZero() && t.dnsDone.IsZero():
+		return info.DNSTimeout
+	case !t.connectStart.IsZero() && t.connectDone.IsZero():
+		return info.ConnectTimeout
+	case !t.tlsStart.IsZero() && t.tlsDone.IsZero():
+		return info.TLSTimeout
+	case t.connectStart.IsZero() && t.wroteRequest.IsZero() && !t.dnsStart.IsZero():
+		// The lookup completed, but the dial did not start in time
+		return info.ConnectTimeout
+	}
+	return info.ResponseTimeout
+}
+
+func between(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Responds after 20ms, and completes the body 10ms later
+func slowHandler(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(20 * time.Millisecond)
+	w.Write([]byte("first"))
+	w.(http.Flusher).Flush()
+	time.Sleep(10 * time.Millisecond)
+	w.Write([]byte("last"))
+}
+
+// Test the phases recorded by the http prober, looking up the host name
+func TestTracer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(slowHandler))
+	defer ts.Close()
+
+	res := probeOnce(t, Website{Url: strings.Replace(ts.URL, "127.0.0.1", "localhost", 1), Timeout: time.Second})
+	if !res.Success || res.Timing == nil {
+		t.Fatalf("got success %v (%s)", res.Success, res.Reason)
+	}
+	tm := res.Timing
+	if tm.DNS <= 0 || tm.Connect <= 0 || tm.TLS != 0 {
+		t.Errorf("got dns %v, connect %v and tls %v", tm.DNS, tm.Connect, tm.TLS)
+	}
+	if tm.Server < 20*time.Millisecond || tm.Transfer < 10*time.Millisecond {
+		t.Errorf("got server %v and transfer %v, expected at least 20ms and 10ms", tm.Server, tm.Transfer)
+	}
+	if sum := tm.DNS + tm.Connect + tm.Server + tm.Transfer; tm.Total < sum {
+		t.Errorf("got total %v, less than the phases %v", tm.Total, sum)
+	}
+}
+
+// Test the TLS handshake, and that a reused connection has no connection phases
+func TestTracerTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(slowHandler))
+	defer ts.Close()
+	client := ts.Client()
+
+	for j, reused := range []bool{false, true} {
+		tr := &tracer{}
+		req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), tr.clientTrace()))
+		tr.start = time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		tm := tr.timing(time.Now())
+
+		if tm.Reused != reused {
+			t.Errorf("request %d: got reused %v", j, tm.Reused)
+		}
+		if connected := tm.Connect > 0 && tm.TLS > 0; connected == reused {
+			t.Errorf("request %d: got connect %v and tls %v on a reused connection %v", j, tm.Connect, tm.TLS, reused)
+		}
+		if tm.Server < 20*time.Millisecond || tm.Transfer < 10*time.Millisecond {
+			t.Errorf("request %d: got server %v and transfer %v", j, tm.Server, tm.Transfer)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+	"github.com/iwita/monitoring-website-stats/pkg/sketch"
+)
+
+// Window is a time window over which the statistics of every website are calculated
+type Window struct {
+	// Identifies the window, e.g. in the output and when selecting the alerting window
+	Name     string
+	Duration time.Duration
+	// How often Snapshot recalculates the results of the window, by default every time
+	Refresh time.Duration
+	// The percentiles of the response times, by default info.DefaultPercentiles
+	Percentiles []float64
+	// When set, the window is stored in quantile sketches of this relative accuracy,
+	// instead of keeping every response
+	Accuracy float64
+	// The upper bounds of the histogram buckets, by default info.DefaultBuckets
+	Buckets []time.Duration
+}
+
+// The windows used, when none are configured
+var DefaultWindows = []Window{
+	{Name: "2m", Duration: 2 * time.Minute},
+	{Name: "10m", Duration: 10 * time.Minute},
+	{Name: "1h", Duration: time.Hour},
+}
+
+// The window the availability alert is evaluated on, when none is configured
+const DefaultAlertWindow = "2m"
+
+// SetWindows replaces the time windows of the monitor and selects the alerting window.
+// It must be called before any statistics are collected
+func (m *Monitor) SetWindows(windows []Window, alertWindow string) error {
+	if len(windows) == 0 {
+		return fmt.Errorf("no windows defined")
+	}
+	found := false
+	names := make(map[string]bool, len(windows))
+	for _, w := range windows {
+		if w.Duration <= 0 {
+			return fmt.Errorf("window %q: duration must be positive", w.Name)
+		}
+		if names[w.Name] {
+			return fmt.Errorf("duplicate window %q", w.Name)
+		}
+		for _, p := range w.Percentiles {
+			if err := info.ValidatePercentile(p); err != nil {
+				return fmt.Errorf("window %q: %v", w.Name, err)
+			}
+		}
+		if w.Accuracy != 0 {
+			if _, err := sketch.New(w.Accuracy, sketch.DefaultMaxBins); err != nil {
+				return fmt.Errorf("window %q: %v", w.Name, err)
+			}
+		}
+		if err := info.ValidateBuckets(w.Buckets); err != nil {
+			return fmt.Errorf("window %q: %v", w.Name, err)
+		}
+		names[w.Name] = true
+		found = found || w.Name == alertWindow
+	}
+	if !found {
+		return fmt.Errorf("alerting window %q is not defined", alertWindow)
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Windows = windows
+	m.AlertWindow = alertWindow
+	return nil
+}
+
+// Returns the index of the window with the given name, or -1
+func (m *Monitor) WindowIndex(name string) int {
+	for i, w := range m.Windows {
+		if w.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Creates the statistics of a website for every window of the monitor
+func (m *Monitor) newStatistics(wb Website) *Statistics {
+	interval := wb.interval()
+	s := &Statistics{
+		Windows: make([]*info.Info, len(m.Windows)),
+		names:   make(map[string]int, len(m.Windows)),
+	}
+	for i, w := range m.Windows {
+		s.Windows[i] = info.NewInfo(w.Duration, interval, w.Name == m.AlertWindow)
+		if len(w.Percentiles) > 0 {
+			s.Windows[i].Percentiles = w.Percentiles
+		}
+		// The accuracy and the buckets have been validated by SetWindows
+		if w.Accuracy != 0 {
+			s.Windows[i].UseSketch(w.Accuracy)
+		}
+		if len(w.Buckets) > 0 {
+			s.Windows[i].SetBuckets(w.Buckets)
+		}
+		s.names[w.Name] = i
+		if w.Name == m.AlertWindow {
+			s.alertWindow = s.Windows[i]
+		}
+	}
+	s.OverallInfo = info.NewInfo(0, interval, false)
+	s.OverallInfo.UseSketch(sketch.DefaultAccuracy)
+	// The default resolutions and accuracy are valid
+	s.History, _ = info.NewRollup(info.DefaultResolutions, sketch.DefaultAccuracy)
+	s.setApdex(wb.Apdex)
+	s.setSLOs(wb.SLOs, interval)
+	return s
+}
+
+// Returns the statistics of the window with the given name, or nil
+func (s *Statistics) Window(name string) *info.Info {
+	if i, ok := s.names[name]; ok {
+		return s.Windows[i]
+	}
+	return nil
+}
+
+// Returns the statistics of the window the availability alert is evaluated on
+func (s *Statistics) AlertInfo() *info.Info {
+	return s.alertWindow
+}
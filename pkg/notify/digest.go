@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+)
+
+// Digest summarizes the websites routed to a notifier over a period, e.g. the past day
+type Digest struct {
+	From     time.Time
+	To       time.Time
+	Websites []WebsiteDigest
+}
+
+// WebsiteDigest summarizes a single website over the period of a digest
+type WebsiteDigest struct {
+	Website string
+	// The number of checks, and the ratio of the successful ones between 0 and 1
+	Checks       int
+	Availability float64
+	// The 90th percentile of the response times of the successful checks, zero when none succeeded
+	P90 time.Duration
+	// The incidents that overlap the period, and their statistics within it
+	Incidents []alert.Incident
+	Stats     alert.IncidentStats
+}
+
+// Digester is a notifier that also sends a periodic digest
+type Digester interface {
+	Notifier
+	// Returns when the next digest is due after the given time, zero when no digests are sent
+	NextDigest(after time.Time) time.Time
+	SendDigest(d Digest) error
+}
+
+// Returns the digest as plain text
+func (d Digest) String() string {
+	var res strings.Builder
+	res.WriteString(fmt.Sprintf("From %v to %v\n", d.From.Format("2006-01-02 15:04"), d.To.Format("2006-01-02 15:04")))
+	for _, w := range d.Websites {
+		res.WriteString(fmt.Sprintf("\n%s\n", w.Website))
+		if w.Checks == 0 {
+			res.WriteString("  No checks\n")
+		} else if w.P90 == 0 {
+			res.WriteString(fmt.Sprintf("  Checks: %d, availability: %.2f%%\n", w.Checks, w.Availability*100))
+		} else {
+			res.WriteString(fmt.Sprintf("  Checks: %d, availability: %.2f%%, p90: %v\n", w.Checks, w.Availability*100, w.P90.Round(time.Microsecond)))
+		}
+		s := w.Stats
+		res.WriteString(fmt.Sprintf("  Incidents: %d, downtime: %v, MTTR: %v, MTBF: %v\n",
+			s.Incidents, s.Downtime.Round(time.Second), s.MTTR.Round(time.Second), s.MTBF.Round(time.Second)))
+		for _, in := range w.Incidents {
+			end := "ongoing"
+			if !in.Ongoing() {
+				end = in.End.Format("2006-01-02 15:04:05")
+			}
+			res.WriteString(fmt.Sprintf("  %v - %s (%v) %s\n", in.Start.Format("2006-01-02 15:04:05"), end,
+				in.Duration(d.To).Round(time.Second), in.Reason))
+		}
+	}
+	return res.String()
+}
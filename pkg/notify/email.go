@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// How the connection to the SMTP server is encrypted
+const (
+	// The server must support STARTTLS
+	StartTLSRequired = "required"
+	// STARTTLS is used when the server supports it
+	StartTLSOptional = "optional"
+	// The connection is never encrypted, e.g. for a local relay
+	StartTLSDisabled = "disabled"
+)
+
+// Timeout of an email delivery, when none is given
+const DefaultEmailTimeout = 10 * time.Second
+
+// Email sends an email for every transition, and optionally a daily digest,
+// through an SMTP server
+type Email struct {
+	// The address (host:port) of the SMTP server
+	Addr string
+	// The credentials of PLAIN authentication, no authentication when the username is empty.
+	// They are only sent over an encrypted connection, or to a server on localhost
+	Username string
+	Password string
+	From     string
+	To       []string
+	// One of StartTLSRequired (default), StartTLSOptional or StartTLSDisabled
+	StartTLS string
+	// Used for the STARTTLS handshake, by default the certificate of the host is verified
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+	// Whether every transition is emailed, otherwise only the digests are sent
+	Alerts bool
+	// Whether a digest is sent every day, DigestAt after midnight (local time)
+	Digest   bool
+	DigestAt time.Duration
+}
+
+// Returns a notifier emailing every transition through the SMTP server,
+// requiring STARTTLS
+func NewEmail(addr, from string, to []string) *Email {
+	return &Email{
+		Addr:     addr,
+		From:     from,
+		To:       to,
+		StartTLS: StartTLSRequired,
+		Timeout:  DefaultEmailTimeout,
+		Alerts:   true,
+	}
+}
+
+func (n *Email) Notify(e Event) error {
+	if !n.Alerts {
+		return nil
+	}
+	state := e.NewState
+	if e.Resolved() {
+		state = "RESOLVED"
+	}
+	subject := fmt.Sprintf("[%s] %s %s", state, e.Website, e.Source())
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("Website: %s\n", e.Website))
+	body.WriteString(fmt.Sprintf("Alert: %s\n", e.Source()))
+	body.WriteString(fmt.Sprintf("State: %s -> %s, at %v\n", e.OldState, e.NewState, e.Time.Format("2006-01-02 15:04:05")))
+	body.WriteString(fmt.Sprintf("%s since: %v (%v)\n", e.OldState, e.Since.Format("2006-01-02 15:04:05"), e.Time.Sub(e.Since).Round(time.Second)))
+	body.WriteString(fmt.Sprintf("Availability: %.2f%%\n", e.Availability*100))
+	if e.Reason != "" {
+		body.WriteString(fmt.Sprintf("Reason: %s\n", e.Reason))
+	}
+	return n.send(subject, body.String())
+}
+
+func (n *Email) NextDigest(after time.Time) time.Time {
+	if !n.Digest {
+		return time.Time{}
+	}
+	y, m, d := after.Date()
+	next := time.Date(y, m, d, 0, 0, 0, 0, after.Location()).Add(n.DigestAt)
+	if !next.After(after) {
+		next = time.Date(y, m, d+1, 0, 0, 0, 0, after.Location()).Add(n.DigestAt)
+	}
+	return next
+}
+
+func (n *Email) SendDigest(d Digest) error {
+	subject := fmt.Sprintf("Monitoring digest %v", d.To.Format("2006-01-02"))
+	return n.send(subject, d.String())
+}
+
+// Sends a plain text email to every recipient
+func (n *Email) send(subject, body string) error {
+	host, _, err := net.SplitHostPort(n.Addr)
+	if err != nil {
+		return err
+	}
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = DefaultEmailTimeout
+	}
+	conn, err := net.DialTimeout("tcp", n.Addr, timeout)
+	if err != nil {
+		return err
+	}
+	// The whole delivery must complete within the timeout
+	conn.SetDeadline(time.Now().Add(timeout))
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if n.StartTLS != StartTLSDisabled {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			config := n.TLSConfig
+			if config == nil {
+				config = &tls.Config{ServerName: host}
+			}
+			if err := c.StartTLS(config); err != nil {
+				return err
+			}
+		} else if n.StartTLS != StartTLSOptional {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", n.Addr)
+		}
+	}
+	if n.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.Username, n.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(envelope(n.From)); err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		if err := c.Rcpt(envelope(to)); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	headers := []string{
+		"From: " + n.From,
+		"To: " + strings.Join(n.To, ", "),
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+	}
+	if _, err := fmt.Fprintf(w, "%s\r\n\r\n%s", strings.Join(headers, "\r\n"), body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Returns the address the SMTP server delivers to, e.g. oncall@example.com for
+// "On call <oncall@example.com>", which is kept in the headers
+func envelope(address string) string {
+	a, err := mail.ParseAddress(address)
+	if err != nil {
+		return address
+	}
+	return a.Address
+}
@@ -0,0 +1,282 @@
+package notify
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+)
+
+// smtpServer is a local stand-in of an SMTP server, that records the messages it receives
+type smtpServer struct {
+	l net.Listener
+	// STARTTLS is offered when set
+	tls *tls.Config
+	// AUTH PLAIN is required when set
+	username, password string
+
+	mutex    sync.Mutex
+	messages []smtpMessage
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+	// Whether the message was received over STARTTLS
+	tls bool
+}
+
+func newSMTPServer(t *testing.T, config *tls.Config, username, password string) *smtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{l: l, tls: config, username: username, password: password}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) Close() {
+	s.l.Close()
+}
+
+// Returns the messages received so far
+func (s *smtpServer) received() []smtpMessage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	reply("220 localhost ESMTP stand-in")
+	var msg smtpMessage
+	authenticated := s.username == ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			ext := []string{"localhost"}
+			if s.tls != nil && !msg.tls {
+				ext = append(ext, "STARTTLS")
+			}
+			if s.username != "" {
+				ext = append(ext, "AUTH PLAIN")
+			}
+			for j, e := range ext {
+				sep := "-"
+				if j == len(ext)-1 {
+					sep = " "
+				}
+				reply("250%s%s", sep, e)
+			}
+		case "STARTTLS":
+			reply("220 ready to start TLS")
+			tc := tls.Server(conn, s.tls)
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			conn, r, msg.tls = tc, bufio.NewReader(tc), true
+		case "AUTH":
+			fields := strings.Fields(line)
+			creds, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			if string(creds) != "\x00"+s.username+"\x00"+s.password {
+				reply("535 authentication failed")
+				continue
+			}
+			authenticated = true
+			reply("235 authenticated")
+		case "MAIL":
+			if !authenticated {
+				reply("530 authentication required")
+				continue
+			}
+			msg.from = strings.Trim(strings.SplitN(line, ":", 2)[1], "<>")
+			reply("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.SplitN(line, ":", 2)[1], "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			msg.data = data.String()
+			s.mutex.Lock()
+			s.messages = append(s.messages, msg)
+			s.mutex.Unlock()
+			msg = smtpMessage{tls: msg.tls}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// Returns the certificate of a test server, valid for 127.0.0.1, and a client configuration trusting it
+func testCertificate() (server, client *tls.Config) {
+	ts := httptest.NewTLSServer(nil)
+	defer ts.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	return &tls.Config{Certificates: ts.TLS.Certificates}, &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+}
+
+func TestEmail(t *testing.T) {
+	serverTLS, clientTLS := testCertificate()
+	s := newSMTPServer(t, serverTLS, "monitor", "secret")
+	defer s.Close()
+
+	n := NewEmail(s.l.Addr().String(), "Monitoring <monitor@example.com>", []string{"On call <oncall@example.com>", "team@example.com"})
+	n.Username, n.Password = "monitor", "secret"
+	n.TLSConfig = clientTLS
+	down := testEvent()
+	if err := n.Notify(down); err != nil {
+		t.Fatal(err)
+	}
+	up := down
+	up.OldState, up.NewState, up.Reason, up.Since = "DOWN", "UP", "", down.Time
+	up.Time = down.Time.Add(time.Minute)
+	if err := n.Notify(up); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := s.received()
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, expected 2", len(msgs))
+	}
+	if m := msgs[0]; !m.tls || m.from != "monitor@example.com" || strings.Join(m.to, ",") != "oncall@example.com,team@example.com" {
+		t.Errorf("got a message from %s to %v over tls %v", m.from, m.to, m.tls)
+	}
+	for j, expected := range [][]string{
+		{"From: Monitoring <monitor@example.com>\r\nTo: On call <oncall@example.com>, team@example.com\r\n", "Subject: [DOWN] https://www.example.com availability\r\n", "State: UP -> DOWN", "Reason: status 503"},
+		{"Subject: [RESOLVED] https://www.example.com availability\r\n", "State: DOWN -> UP", "DOWN since: 2024-05-01 12:03:00 (1m0s)"},
+	} {
+		for _, e := range expected {
+			if !strings.Contains(msgs[j].data, e) {
+				t.Errorf("message %d does not contain %q:\n%s", j, e, msgs[j].data)
+			}
+		}
+	}
+
+	n.Password = "wrong"
+	if err := n.Notify(down); err == nil {
+		t.Errorf("expected an error for wrong credentials")
+	}
+	// In digest mode, the transitions are not emailed
+	n.Password, n.Alerts = "secret", false
+	if err := n.Notify(down); err != nil || len(s.received()) != 2 {
+		t.Errorf("got %v and %d messages, expected no email", err, len(s.received()))
+	}
+}
+
+func TestEmailStartTLS(t *testing.T) {
+	s := newSMTPServer(t, nil, "", "")
+	defer s.Close()
+
+	n := NewEmail(s.l.Addr().String(), "monitor@example.com", []string{"oncall@example.com"})
+	if err := n.Notify(testEvent()); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("got %v, expected STARTTLS to be required", err)
+	}
+	for _, mode := range []string{StartTLSOptional, StartTLSDisabled} {
+		n.StartTLS = mode
+		if err := n.Notify(testEvent()); err != nil {
+			t.Errorf("%s: %v", mode, err)
+		}
+	}
+	if msgs := s.received(); len(msgs) != 2 || msgs[0].tls {
+		t.Errorf("got %d messages, expected 2 unencrypted", len(msgs))
+	}
+}
+
+func TestDigest(t *testing.T) {
+	n := NewEmail("127.0.0.1:25", "monitor@example.com", nil)
+	at := time.Date(2024, 5, 1, 7, 0, 0, 0, time.Local)
+	if next := n.NextDigest(at); !next.IsZero() {
+		t.Errorf("got %v, expected no digests", next)
+	}
+	n.Digest, n.DigestAt = true, 8*time.Hour
+	for _, test := range []struct {
+		after, expected time.Time
+	}{
+		{at, at.Add(time.Hour)},
+		{at.Add(time.Hour), at.Add(25 * time.Hour)},
+		{at.Add(2 * time.Hour), at.Add(25 * time.Hour)},
+	} {
+		if next := n.NextDigest(test.after); !next.Equal(test.expected) {
+			t.Errorf("after %v: got %v, expected %v", test.after, next, test.expected)
+		}
+	}
+
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	d := Digest{
+		From: start,
+		To:   start.Add(24 * time.Hour),
+		Websites: []WebsiteDigest{{
+			Website:      "https://www.example.com",
+			Checks:       86400,
+			Availability: 0.99,
+			P90:          120 * time.Millisecond,
+			Incidents: []alert.Incident{{
+				Start:  start.Add(2 * time.Hour),
+				End:    start.Add(2*time.Hour + 3*time.Minute),
+				Reason: "status 503",
+			}},
+			Stats: alert.IncidentStats{Incidents: 1, Downtime: 3 * time.Minute, MTTR: 3 * time.Minute, MTBF: 24*time.Hour - 3*time.Minute},
+		}, {
+			Website: "https://new.example.com",
+		}},
+	}
+	expected := `From 2024-05-01 08:00 to 2024-05-02 08:00
+
+https://www.example.com
+  Checks: 86400, availability: 99.00%, p90: 120ms
+  Incidents: 1, downtime: 3m0s, MTTR: 3m0s, MTBF: 23h57m0s
+  2024-05-01 10:00:00 - 2024-05-01 10:03:00 (3m0s) status 503
+
+https://new.example.com
+  No checks
+  Incidents: 0, downtime: 0s, MTTR: 0s, MTBF: 0s
+`
+	if d.String() != expected {
+		t.Errorf("got\n%s\nexpected\n%s", d.String(), expected)
+	}
+}
@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// File appends every event to a file, as a line of json
+type File struct {
+	Path string
+}
+
+func NewFile(path string) *File {
+	return &File{Path: path}
+}
+
+// The file is opened for every event, so that it can be rotated while monitoring
+func (n *File) Notify(e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
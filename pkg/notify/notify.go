@@ -0,0 +1,83 @@
+// Package notify delivers the state transitions of the alerts of the websites,
+// so that nobody has to watch the display in order to learn that a website is down
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// The kinds of alerts an event may come from
+const (
+	AvailabilityAlert = "availability"
+	CertificateAlert  = "certificate"
+	SLOAlert          = "slo"
+)
+
+// Event is a state transition of an alert of a website
+type Event struct {
+	Time    time.Time `json:"time"`
+	Website string    `json:"website"`
+	// The kind of alert, and the name of the objective of slo alerts
+	Alert string `json:"alert"`
+	Name  string `json:"name,omitempty"`
+	// The states of the alert before and after the transition, e.g. UP and DOWN
+	OldState string `json:"oldState"`
+	NewState string `json:"newState"`
+	// The availability of the alerting window, between 0 and 1
+	Availability float64 `json:"availability"`
+	// When the old state started
+	Since time.Time `json:"since"`
+	// Why the alert fired, empty when it recovered
+	Reason string `json:"reason,omitempty"`
+}
+
+// Returns the alert the event comes from, e.g. "availability" or "slo fast"
+func (e Event) Source() string {
+	if e.Name != "" {
+		return fmt.Sprintf("%s %s", e.Alert, e.Name)
+	}
+	return e.Alert
+}
+
+// Reports whether the event is a recovery, i.e. the alert stopped firing
+func (e Event) Resolved() bool {
+	return e.NewState == "UP" || e.NewState == "OK" || e.NewState == "VALID"
+}
+
+// Returns a single line describing the event
+func (e Event) String() string {
+	s := fmt.Sprintf("%s %s %s: %s -> %s after %v, availability %.2f%%",
+		e.Time.Format("2006-01-02 15:04:05"), e.Website, e.Source(), e.OldState, e.NewState,
+		e.Time.Sub(e.Since).Round(time.Second), e.Availability*100)
+	if e.Reason != "" {
+		s += ", " + e.Reason
+	}
+	return s
+}
+
+// Notifier delivers the events of the websites it is routed
+type Notifier interface {
+	Notify(e Event) error
+}
+
+// Writer writes every event as a line of text
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Returns a notifier writing to the standard output
+func NewStdout() *Writer {
+	return NewWriter(os.Stdout)
+}
+
+func (n *Writer) Notify(e Event) error {
+	_, err := fmt.Fprintln(n.w, e.String())
+	return err
+}
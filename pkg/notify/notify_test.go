@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEvent() Event {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return Event{
+		Time:         since.Add(3 * time.Minute),
+		Website:      "https://www.example.com",
+		Alert:        AvailabilityAlert,
+		OldState:     "UP",
+		NewState:     "DOWN",
+		Availability: 0.5,
+		Since:        since,
+		Reason:       "status 503",
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Notify(testEvent()); err != nil {
+		t.Fatal(err)
+	}
+	expected := "2024-05-01 12:03:00 https://www.example.com availability: UP -> DOWN after 3m0s, availability 50.00%, status 503\n"
+	if buf.String() != expected {
+		t.Errorf("got %q, expected %q", buf.String(), expected)
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	n := NewFile(filepath.Join(dir, "events.jsonl"))
+	e := testEvent()
+	for j := 0; j < 2; j++ {
+		if err := n.Notify(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(n.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := 0
+	for s := bufio.NewScanner(f); s.Scan(); lines++ {
+		var got Event
+		if err := json.Unmarshal(s.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != e {
+			t.Errorf("got %+v, expected %+v", got, e)
+		}
+	}
+	if lines != 2 {
+		t.Errorf("got %d lines, expected 2", lines)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var received []Event
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("got %s with headers %v", r.Method, r.Header)
+		}
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		received = append(received, e)
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	n := NewWebhook(ts.URL, map[string]string{"Authorization": "Bearer token"}, 0)
+	if err := n.Notify(testEvent()); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || received[0].Reason != "status 503" {
+		t.Errorf("got %+v", received)
+	}
+	status = http.StatusBadGateway
+	if err := n.Notify(testEvent()); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("got %v, expected an error for the status 502", err)
+	}
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Timeout of a webhook request, when none is given
+const DefaultWebhookTimeout = 5 * time.Second
+
+// Webhook posts every event as json to a url, e.g. of a chat or paging service
+type Webhook struct {
+	Url     string
+	Headers map[string]string
+	client  *http.Client
+}
+
+func NewWebhook(url string, headers map[string]string, timeout time.Duration) *Webhook {
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	return &Webhook{
+		Url:     url,
+		Headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Any status other than 2xx is an error
+func (n *Webhook) Notify(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, n.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Read the body, so that the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: status %d", n.Url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package sketch implements a mergeable quantile sketch in the style of DDSketch.
+//
+// Values are counted in logarithmically sized bins, so that every quantile
+// is returned with a relative error of at most the accuracy of the sketch,
+// using memory that depends on the range of the values instead of their number.
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The relative accuracy used, when none is given
+const DefaultAccuracy = 0.01
+
+// The maximum number of bins, when none is given. With the default accuracy
+// it spans values that differ by a factor of about 10^8
+const DefaultMaxBins = 1024
+
+// Sketch summarizes a stream of non negative values.
+// It is not safe for concurrent use
+type Sketch struct {
+	accuracy float64
+	gamma    float64
+	logGamma float64
+	maxBins  int
+	// The number of values per bin key, a value v is counted in the bin ceil(log_gamma(v))
+	bins map[int]uint64
+	// The number of values too small to be counted in a bin
+	zeros    uint64
+	count    uint64
+	min, max float64
+}
+
+// Creates a sketch with the given relative accuracy, e.g. 0.01 for 1%.
+// Once more than maxBins bins are needed, the lowest bins are merged,
+// and only the quantiles in the merged bins lose their accuracy
+func New(accuracy float64, maxBins int) (*Sketch, error) {
+	if math.IsNaN(accuracy) || accuracy <= 0 || accuracy >= 1 {
+		return nil, fmt.Errorf("invalid accuracy %v, must be between 0 and 1", accuracy)
+	}
+	if maxBins <= 0 {
+		return nil, fmt.Errorf("invalid number of bins %d", maxBins)
+	}
+	gamma := (1 + accuracy) / (1 - accuracy)
+	return &Sketch{
+		accuracy: accuracy,
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		maxBins:  maxBins,
+		bins:     make(map[int]uint64),
+	}, nil
+}
+
+// Returns the relative accuracy of the quantiles of the sketch
+func (s *Sketch) Accuracy() float64 {
+	return s.accuracy
+}
+
+// Returns the number of values added
+func (s *Sketch) Count() uint64 {
+	return s.count
+}
+
+// Returns the exact minimum and maximum of the values added
+func (s *Sketch) Min() float64 { return s.min }
+func (s *Sketch) Max() float64 { return s.max }
+
+// Adds a value to the sketch in constant time. Negative values are counted as zero
+func (s *Sketch) Add(v float64) {
+	if v < 0 || math.IsNaN(v) {
+		v = 0
+	}
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count++
+	if v < 1 {
+		// Values below 1 (e.g. nanoseconds) are not worth a bin of their own
+		s.zeros++
+		return
+	}
+	s.bins[s.key(v)]++
+	if len(s.bins) > s.maxBins {
+		s.collapse()
+	}
+}
+
+// Adds the values of another sketch of the same accuracy
+func (s *Sketch) Merge(o *Sketch) error {
+	if o == nil || o.count == 0 {
+		return nil
+	}
+	if o.gamma != s.gamma {
+		return fmt.Errorf("cannot merge sketches of accuracy %v and %v", s.accuracy, o.accuracy)
+	}
+	if s.count == 0 || o.min < s.min {
+		s.min = o.min
+	}
+	if s.count == 0 || o.max > s.max {
+		s.max = o.max
+	}
+	s.count += o.count
+	s.zeros += o.zeros
+	for k, c := range o.bins {
+		s.bins[k] += c
+	}
+	for len(s.bins) > s.maxBins {
+		s.collapse()
+	}
+	return nil
+}
+
+// Returns the q quantile (0 <= q <= 1) of the values added, using the nearest rank.
+// It is within the accuracy of the sketch from the exact quantile
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+	// The index of the value in the sorted values
+	rank := uint64(math.Ceil(q*float64(s.count))) - 1
+	if rank < s.zeros {
+		return s.min
+	}
+	cum := s.zeros
+	for _, k := range s.keys() {
+		cum += s.bins[k]
+		if cum > rank {
+			return math.Min(math.Max(s.value(k), s.min), s.max)
+		}
+	}
+	return s.max
+}
+
+// Returns the bin of a value
+func (s *Sketch) key(v float64) int {
+	return int(math.Ceil(math.Log(v) / s.logGamma))
+}
+
+// Returns the value that represents a bin, within the accuracy from every value in it
+func (s *Sketch) value(k int) float64 {
+	return 2 * math.Pow(s.gamma, float64(k)) / (s.gamma + 1)
+}
+
+func (s *Sketch) keys() []int {
+	keys := make([]int, 0, len(s.bins))
+	for k := range s.bins {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// Merges the two lowest bins
+func (s *Sketch) collapse() {
+	keys := s.keys()
+	if len(keys) < 2 {
+		return
+	}
+	s.bins[keys[1]] += s.bins[keys[0]]
+	delete(s.bins, keys[0])
+}
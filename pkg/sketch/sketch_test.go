@@ -0,0 +1,79 @@
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Test that the quantiles are within the accuracy of the exact ones
+func TestQuantileAccuracy(t *testing.T) {
+	s, err := New(DefaultAccuracy, DefaultMaxBins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(1))
+	values := make([]float64, 10000)
+	for i := range values {
+		// Response times between 1ms and about 1s, in nanoseconds
+		values[i] = math.Exp(r.Float64()*7) * 1e6
+		s.Add(values[i])
+	}
+	sort.Float64s(values)
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.95, 0.99, 0.999} {
+		exact := values[int(math.Ceil(q*float64(len(values))))-1]
+		got := s.Quantile(q)
+		if math.Abs(got-exact) > DefaultAccuracy*exact {
+			t.Errorf("q%v: got %v, want %v within %v", q, got, exact, DefaultAccuracy)
+		}
+	}
+	if s.Quantile(0) != values[0] || s.Quantile(1) != values[len(values)-1] {
+		t.Errorf("got min %v and max %v, want %v and %v", s.Quantile(0), s.Quantile(1), values[0], values[len(values)-1])
+	}
+}
+
+// Test that merging two sketches is the same as adding every value to one
+func TestMerge(t *testing.T) {
+	a, _ := New(DefaultAccuracy, DefaultMaxBins)
+	b, _ := New(DefaultAccuracy, DefaultMaxBins)
+	all, _ := New(DefaultAccuracy, DefaultMaxBins)
+	for i := 1; i <= 1000; i++ {
+		if i%2 == 0 {
+			a.Add(float64(i))
+		} else {
+			b.Add(float64(i))
+		}
+		all.Add(float64(i))
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []float64{0.1, 0.5, 0.99} {
+		if a.Quantile(q) != all.Quantile(q) {
+			t.Errorf("q%v: got %v, want %v", q, a.Quantile(q), all.Quantile(q))
+		}
+	}
+	other, _ := New(0.05, DefaultMaxBins)
+	if err := a.Merge(other); err != nil {
+		t.Errorf("merging an empty sketch: %v", err)
+	}
+	other.Add(1)
+	if err := a.Merge(other); err == nil {
+		t.Errorf("merged sketches of different accuracy")
+	}
+}
+
+// Test that the number of bins stays bounded
+func TestMaxBins(t *testing.T) {
+	s, _ := New(DefaultAccuracy, 10)
+	for v := 1.0; v < 1e9; v *= 2 {
+		s.Add(v)
+	}
+	if len(s.bins) > 10 {
+		t.Errorf("got %d bins, want at most 10", len(s.bins))
+	}
+	if got, want := s.Quantile(1), s.Max(); got != want {
+		t.Errorf("got max %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+	"github.com/iwita/monitoring-website-stats/pkg/monitor"
+)
+
+// The window label of the statistics since monitoring started
+const overallWindow = "overall"
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// A result of a website, together with the labels that identify it
+type promSeries struct {
+	labels string
+	res    *info.Result
+	// Whether the result is since monitoring started, its counts never decrease
+	overall bool
+}
+
+// Prints the most recently computed results in the Prometheus text exposition format,
+// so that they can be collected e.g. through the textfile collector of the node exporter
+func (p *printer) printPrometheus(snap *monitor.Snapshot) {
+	series := make([]promSeries, 0)
+	for _, ws := range snap.Websites {
+		for j, w := range snap.Windows {
+			if j < len(ws.Results) && ws.Results[j] != nil {
+				series = append(series, promSeries{promLabels(ws.Url, w.Name), ws.Results[j], false})
+			}
+		}
+		if ws.Overall != nil {
+			series = append(series, promSeries{promLabels(ws.Url, overallWindow), ws.Overall, true})
+		}
+	}
+
+	// The counts of the other windows decrease as responses leave them,
+	// which would be taken for counter resets, so they are gauges instead
+	p.promHeader("website_response_time_seconds", "histogram", "Response times of the checks since monitoring started.")
+	for _, s := range series {
+		if !s.overall {
+			continue
+		}
+		h := s.res.Histogram
+		for j, bound := range h.Bounds {
+			fmt.Fprintf(p.w, "website_response_time_seconds_bucket{%s,le=\"%s\"} %d\n", s.labels, promFloat(bound.Seconds()), h.Counts[j])
+		}
+		fmt.Fprintf(p.w, "website_response_time_seconds_bucket{%s,le=\"+Inf\"} %d\n", s.labels, h.Counts[len(h.Counts)-1])
+		fmt.Fprintf(p.w, "website_response_time_seconds_sum{%s} %s\n", s.labels, promFloat(h.Sum.Seconds()))
+		fmt.Fprintf(p.w, "website_response_time_seconds_count{%s} %d\n", s.labels, h.Counts[len(h.Counts)-1])
+	}
+	p.promHeader("website_window_response_time_responses", "gauge", "Responses within the window up to each response time.")
+	for _, s := range series {
+		if s.overall {
+			continue
+		}
+		h := s.res.Histogram
+		for j, bound := range h.Bounds {
+			fmt.Fprintf(p.w, "website_window_response_time_responses{%s,le=\"%s\"} %d\n", s.labels, promFloat(bound.Seconds()), h.Counts[j])
+		}
+		fmt.Fprintf(p.w, "website_window_response_time_responses{%s,le=\"+Inf\"} %d\n", s.labels, h.Counts[len(h.Counts)-1])
+	}
+	p.promHeader("website_response_time_quantile_seconds", "gauge", "Configured percentiles of the response times within the window.")
+	for _, s := range series {
+		for _, pr := range s.res.Percentiles {
+			fmt.Fprintf(p.w, "website_response_time_quantile_seconds{%s,quantile=\"%s\"} %s\n", s.labels, promQuantile(pr.Percentile), promFloat(pr.Value.Seconds()))
+		}
+	}
+	p.promGauge(series, "website_response_time_min_seconds", "Minimum response time within the window.",
+		func(res *info.Result) (float64, bool) { return res.Min.Seconds(), timed(res) })
+	p.promGauge(series, "website_response_time_max_seconds", "Maximum response time within the window.",
+		func(res *info.Result) (float64, bool) { return res.Max.Seconds(), timed(res) })
+	p.promGauge(series, "website_response_time_average_seconds", "Average response time within the window.",
+		func(res *info.Result) (float64, bool) { return res.Average.Seconds(), timed(res) })
+	p.promGauge(series, "website_availability_ratio", "Ratio of the window the website was available.",
+		func(res *info.Result) (float64, bool) { return res.Availability / 100, true })
+	p.promGauge(series, "website_response_time_stddev_seconds", "Standard deviation of the response times within the window.",
+		func(res *info.Result) (float64, bool) { return res.StdDev.Seconds(), true })
+	p.promGauge(series, "website_response_time_jitter_seconds", "Mean absolute difference between consecutive response times within the window.",
+		func(res *info.Result) (float64, bool) { return res.Jitter.Seconds(), true })
+	p.promGauge(series, "website_apdex_score", "Apdex score of the window.",
+		func(res *info.Result) (float64, bool) {
+			if res.Apdex == nil {
+				return 0, false
+			}
+			return res.Apdex.Score, true
+		})
+
+	p.promGauge(series, "website_error_ratio", "Ratio of the checks within the window that failed.",
+		func(res *info.Result) (float64, bool) { return res.StatusCodes.ErrorRate, true })
+	p.promHeader("website_status_code_responses", "gauge", "Responses within the window per status code.")
+	for _, s := range series {
+		for _, c := range s.res.StatusCodes.Codes {
+			fmt.Fprintf(p.w, "website_status_code_responses{%s,code=\"%d\"} %d\n", s.labels, c.Code, c.Count)
+		}
+	}
+	p.promHeader("website_status_class_responses", "gauge", "Responses within the window per status class, network errors having no status code.")
+	for _, s := range series {
+		for _, c := range s.res.StatusCodes.Classes {
+			fmt.Fprintf(p.w, "website_status_class_responses{%s,class=\"%s\"} %d\n", s.labels, c.Class, c.Count)
+		}
+	}
+
+	p.promHeader("website_up", "gauge", "Whether the availability alert of the website is not firing.")
+	for _, ws := range snap.Websites {
+		if ws.Ready {
+			up := 1
+			if ws.Alert.AlertState != alert.Available {
+				up = 0
+			}
+			fmt.Fprintf(p.w, "website_up{url=\"%s\"} %d\n", labelEscaper.Replace(ws.Url), up)
+		}
+	}
+	p.printPrometheusIncidents(snap)
+	p.printPrometheusSLOs(snap)
+}
+
+// Prints the statistics of the incidents of every website since monitoring started
+func (p *printer) printPrometheusIncidents(snap *monitor.Snapshot) {
+	stats := make(map[string]alert.IncidentStats, len(snap.Websites))
+	for _, ws := range snap.Websites {
+		if ws.Ready {
+			stats[ws.Url] = ws.Alert.Stats(ws.Alert.Since, snap.Time)
+		}
+	}
+	for _, m := range []struct {
+		name, kind, help string
+		value            func(alert.IncidentStats) string
+	}{
+		{"website_incidents_total", "counter", "Number of times the availability alert fired.",
+			func(s alert.IncidentStats) string { return strconv.Itoa(s.Incidents) }},
+		{"website_downtime_seconds_total", "counter", "Time the availability alert was firing.",
+			func(s alert.IncidentStats) string { return promFloat(s.Downtime.Seconds()) }},
+		{"website_mttr_seconds", "gauge", "Mean time to recovery of the incidents.",
+			func(s alert.IncidentStats) string { return promFloat(s.MTTR.Seconds()) }},
+		{"website_mtbf_seconds", "gauge", "Mean time between the incidents.",
+			func(s alert.IncidentStats) string { return promFloat(s.MTBF.Seconds()) }},
+	} {
+		p.promHeader(m.name, m.kind, m.help)
+		for _, ws := range snap.Websites {
+			if s, ok := stats[ws.Url]; ok {
+				fmt.Fprintf(p.w, "%s{url=\"%s\"} %s\n", m.name, labelEscaper.Replace(ws.Url), m.value(s))
+			}
+		}
+	}
+}
+
+// Prints the error budget, the burn rates and the alert of every objective
+func (p *printer) printPrometheusSLOs(snap *monitor.Snapshot) {
+	type sloSeries struct {
+		labels string
+		res    *monitor.SLOResult
+		state  alert.SLOState
+	}
+	series := make([]sloSeries, 0)
+	for _, ws := range snap.Websites {
+		for _, slo := range ws.SLOs {
+			labels := fmt.Sprintf("url=\"%s\",slo=\"%s\"", labelEscaper.Replace(ws.Url), labelEscaper.Replace(slo.Result.Name))
+			series = append(series, sloSeries{labels, slo.Result, slo.Alert.State})
+		}
+	}
+	if len(series) == 0 {
+		return
+	}
+	p.promHeader("website_slo_objective_ratio", "gauge", "Ratio of the checks that must meet the objective.")
+	for _, s := range series {
+		fmt.Fprintf(p.w, "website_slo_objective_ratio{%s} %s\n", s.labels, promFloat(s.res.Objective))
+	}
+	p.promHeader("website_slo_sli_ratio", "gauge", "Ratio of the checks of the period that met the objective.")
+	for _, s := range series {
+		fmt.Fprintf(p.w, "website_slo_sli_ratio{%s} %s\n", s.labels, promFloat(s.res.SLI))
+	}
+	p.promHeader("website_slo_error_budget_remaining_ratio", "gauge", "Ratio of the error budget of the period that is left.")
+	for _, s := range series {
+		fmt.Fprintf(p.w, "website_slo_error_budget_remaining_ratio{%s} %s\n", s.labels, promFloat(s.res.ErrorBudget))
+	}
+	p.promHeader("website_slo_burn_rate", "gauge", "Rate the error budget is consumed at over the window, relative to the objective.")
+	for _, s := range series {
+		// The windows may be shared by several rules
+		seen := make(map[time.Duration]bool)
+		for _, br := range s.res.BurnRates {
+			for _, w := range []struct {
+				d    time.Duration
+				rate float64
+			}{{br.Long, br.LongRate}, {br.Short, br.ShortRate}} {
+				if !seen[w.d] {
+					seen[w.d] = true
+					fmt.Fprintf(p.w, "website_slo_burn_rate{%s,window=\"%s\"} %s\n", s.labels, shortDuration(w.d), promFloat(w.rate))
+				}
+			}
+		}
+	}
+	p.promHeader("website_slo_alert_firing", "gauge", "Whether a burn-rate alert of the objective is firing.")
+	for _, s := range series {
+		firing := 0
+		if s.state == alert.SLOBurning {
+			firing = 1
+		}
+		fmt.Fprintf(p.w, "website_slo_alert_firing{%s} %d\n", s.labels, firing)
+	}
+}
+
+func (p *printer) promHeader(name, kind, help string) {
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Prints a gauge for every result it has a value for
+func (p *printer) promGauge(series []promSeries, name, help string, value func(*info.Result) (float64, bool)) {
+	p.promHeader(name, "gauge", help)
+	for _, s := range series {
+		if v, ok := value(s.res); ok {
+			fmt.Fprintf(p.w, "%s{%s} %s\n", name, s.labels, promFloat(v))
+		}
+	}
+}
+
+// Reports whether a result has successful responses, which the response times are calculated from
+func timed(res *info.Result) bool {
+	h := res.Histogram
+	return h != nil && h.Counts[len(h.Counts)-1] > 0
+}
+
+func promLabels(url, window string) string {
+	return fmt.Sprintf("url=\"%s\",window=\"%s\"", labelEscaper.Replace(url), labelEscaper.Replace(window))
+}
+
+// Formats a percentile as a quantile, e.g. 99.9 as "0.999" rather than the nearest float
+func promQuantile(p float64) string {
+	return strconv.FormatFloat(p/100, 'g', 12, 64)
+}
+
+func promFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/alert"
+	"github.com/iwita/monitoring-website-stats/pkg/info"
+	"github.com/iwita/monitoring-website-stats/pkg/monitor"
+)
+
+// Returns the result of a window with a successful and a failed response
+func promResult(t *testing.T, start time.Time, duration time.Duration) *info.Result {
+	i := info.NewInfo(duration, time.Second, false)
+	i.Percentiles = []float64{50, 99.9}
+	if err := i.SetBuckets([]time.Duration{100 * time.Millisecond, time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	i.Update(&info.Response{Time: start, Delay: 50 * time.Millisecond, Status: 200, Success: true})
+	i.Update(&info.Response{Time: start.Add(time.Second), Failure: info.ConnectionRefused, Reason: "connection refused"})
+	return i.GetResult()
+}
+
+// Only the histogram since monitoring started is typed as such, the windows are gauges
+const promGolden = `# HELP website_response_time_seconds Response times of the checks since monitoring started.
+# TYPE website_response_time_seconds histogram
+website_response_time_seconds_bucket{url="http://example.com/\"a\"\\b",window="overall",le="0.1"} 1
+website_response_time_seconds_bucket{url="http://example.com/\"a\"\\b",window="overall",le="1"} 1
+website_response_time_seconds_bucket{url="http://example.com/\"a\"\\b",window="overall",le="+Inf"} 1
+website_response_time_seconds_sum{url="http://example.com/\"a\"\\b",window="overall"} 0.05
+website_response_time_seconds_count{url="http://example.com/\"a\"\\b",window="overall"} 1
+# HELP website_window_response_time_responses Responses within the window up to each response time.
+# TYPE website_window_response_time_responses gauge
+website_window_response_time_responses{url="http://example.com/\"a\"\\b",window="1m",le="0.1"} 1
+website_window_response_time_responses{url="http://example.com/\"a\"\\b",window="1m",le="1"} 1
+website_window_response_time_responses{url="http://example.com/\"a\"\\b",window="1m",le="+Inf"} 1
+# HELP website_response_time_quantile_seconds Configured percentiles of the response times within the window.
+# TYPE website_response_time_quantile_seconds gauge
+website_response_time_quantile_seconds{url="http://example.com/\"a\"\\b",window="1m",quantile="0.5"} 0.05
+website_response_time_quantile_seconds{url="http://example.com/\"a\"\\b",window="1m",quantile="0.999"} 0.05
+website_response_time_quantile_seconds{url="http://example.com/\"a\"\\b",window="overall",quantile="0.5"} 0.05
+website_response_time_quantile_seconds{url="http://example.com/\"a\"\\b",window="overall",quantile="0.999"} 0.05
+# HELP website_response_time_min_seconds Minimum response time within the window.
+# TYPE website_response_time_min_seconds gauge
+website_response_time_min_seconds{url="http://example.com/\"a\"\\b",window="1m"} 0.05
+website_response_time_min_seconds{url="http://example.com/\"a\"\\b",window="overall"} 0.05
+# HELP website_response_time_max_seconds Maximum response time within the window.
+# TYPE website_response_time_max_seconds gauge
+website_response_time_max_seconds{url="http://example.com/\"a\"\\b",window="1m"} 0.05
+website_response_time_max_seconds{url="http://example.com/\"a\"\\b",window="overall"} 0.05
+# HELP website_response_time_average_seconds Average response time within the window.
+# TYPE website_response_time_average_seconds gauge
+website_response_time_average_seconds{url="http://example.com/\"a\"\\b",window="1m"} 0.05
+website_response_time_average_seconds{url="http://example.com/\"a\"\\b",window="overall"} 0.05
+# HELP website_availability_ratio Ratio of the window the website was available.
+# TYPE website_availability_ratio gauge
+website_availability_ratio{url="http://example.com/\"a\"\\b",window="1m"} 0
+website_availability_ratio{url="http://example.com/\"a\"\\b",window="overall"} 0
+# HELP website_response_time_stddev_seconds Standard deviation of the response times within the window.
+# TYPE website_response_time_stddev_seconds gauge
+website_response_time_stddev_seconds{url="http://example.com/\"a\"\\b",window="1m"} 0
+website_response_time_stddev_seconds{url="http://example.com/\"a\"\\b",window="overall"} 0
+# HELP website_response_time_jitter_seconds Mean absolute difference between consecutive response times within the window.
+# TYPE website_response_time_jitter_seconds gauge
+website_response_time_jitter_seconds{url="http://example.com/\"a\"\\b",window="1m"} 0
+website_response_time_jitter_seconds{url="http://example.com/\"a\"\\b",window="overall"} 0
+# HELP website_apdex_score Apdex score of the window.
+# TYPE website_apdex_score gauge
+# HELP website_error_ratio Ratio of the checks within the window that failed.
+# TYPE website_error_ratio gauge
+website_error_ratio{url="http://example.com/\"a\"\\b",window="1m"} 0.5
+website_error_ratio{url="http://example.com/\"a\"\\b",window="overall"} 0.5
+# HELP website_status_code_responses Responses within the window per status code.
+# TYPE website_status_code_responses gauge
+website_status_code_responses{url="http://example.com/\"a\"\\b",window="1m",code="200"} 1
+website_status_code_responses{url="http://example.com/\"a\"\\b",window="overall",code="200"} 1
+# HELP website_status_class_responses Responses within the window per status class, network errors having no status code.
+# TYPE website_status_class_responses gauge
+website_status_class_responses{url="http://example.com/\"a\"\\b",window="1m",class="2xx"} 1
+website_status_class_responses{url="http://example.com/\"a\"\\b",window="1m",class="network_error"} 1
+website_status_class_responses{url="http://example.com/\"a\"\\b",window="overall",class="2xx"} 1
+website_status_class_responses{url="http://example.com/\"a\"\\b",window="overall",class="network_error"} 1
+# HELP website_up Whether the availability alert of the website is not firing.
+# TYPE website_up gauge
+website_up{url="http://example.com/\"a\"\\b"} 1
+# HELP website_incidents_total Number of times the availability alert fired.
+# TYPE website_incidents_total counter
+website_incidents_total{url="http://example.com/\"a\"\\b"} 0
+# HELP website_downtime_seconds_total Time the availability alert was firing.
+# TYPE website_downtime_seconds_total counter
+website_downtime_seconds_total{url="http://example.com/\"a\"\\b"} 0
+# HELP website_mttr_seconds Mean time to recovery of the incidents.
+# TYPE website_mttr_seconds gauge
+website_mttr_seconds{url="http://example.com/\"a\"\\b"} 0
+# HELP website_mtbf_seconds Mean time between the incidents.
+# TYPE website_mtbf_seconds gauge
+website_mtbf_seconds{url="http://example.com/\"a\"\\b"} 0
+`
+
+// Test the whole exposition of a website, whose url needs its labels escaped, against the expected output
+func TestPrometheus(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	url := `http://example.com/"a"\b`
+	snap := &monitor.Snapshot{
+		Time:    start.Add(time.Minute),
+		Windows: []monitor.Window{{Name: "1m", Duration: time.Minute}},
+		Websites: []monitor.WebsiteSnapshot{{
+			Url:     url,
+			Ready:   true,
+			Results: []*info.Result{promResult(t, start, time.Minute)},
+			Overall: promResult(t, start, 0),
+			Since:   start,
+			Alert:   &alert.Alert{AlertState: alert.Available, Threshold: 0.8, Since: start},
+		}},
+	}
+	var b bytes.Buffer
+	p := newPrinter(&b, prometheusOutput, &Configs{})
+	if err := p.print(snap); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != promGolden {
+		t.Errorf("got\n%s\nwant\n%s", got, promGolden)
+	}
+}
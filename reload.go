@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/iwita/monitoring-website-stats/pkg/monitor"
+)
+
+// watcher detects changes of the configuration file,
+// by comparing its modification time and size
+type watcher struct {
+	file    string
+	modTime time.Time
+	size    int64
+}
+
+func newWatcher(file string) *watcher {
+	w := &watcher{file: file}
+	w.changed()
+	return w
+}
+
+// Reports whether the file changed since the last call
+func (w *watcher) changed() bool {
+	info, err := os.Stat(w.file)
+	if err != nil {
+		return false
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	return true
+}
+
+// Reads the configuration file again and applies it to the running monitor.
+// An invalid configuration is reported and ignored, so that monitoring continues.
+// The settings that require a restart keep their values of the current configuration
+func reload(dd *monitor.Monitor, current *Configs, file, output string) {
+	cfg, err := loadConfig(file)
+	if err == nil {
+		err = cfg.checkOutput(output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reload failed, keeping the current configuration: %v\n", err)
+		return
+	}
+	if settings := cfg.restartRequired(current); len(settings) > 0 {
+		fmt.Fprintf(os.Stderr, "reload: %s changed, keeping the current ones until a restart\n", strings.Join(settings, ", "))
+	}
+	wbs := make(monitor.Websites, 0, len(cfg.Websites))
+	for _, w := range cfg.Websites {
+		wbs = append(wbs, w.toMonitor())
+	}
+	added, removed, changed := dd.Reload(wbs)
+	// The configuration has already been validated
+	notifiers, _ := cfg.notifiers()
+	if err := dd.SetNotifiers(notifiers); err != nil {
+		fmt.Fprintf(os.Stderr, "reload of the notifiers failed: %v\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "reloaded %s: added [%s], removed [%s], changed [%s]\n", file,
+		strings.Join(added, ", "), strings.Join(removed, ", "), strings.Join(changed, ", "))
+}
+
+// Returns the settings that differ from the current configuration
+// and cannot be applied to a running monitor
+func (cfg *Configs) restartRequired(current *Configs) []string {
+	var settings []string
+	windows, _ := cfg.windows()
+	currentWindows, _ := current.windows()
+	if !reflect.DeepEqual(windows, currentWindows) {
+		settings = append(settings, "windows")
+	}
+	if cfg.alertWindow() != current.alertWindow() {
+		settings = append(settings, "alertWindow")
+	}
+	if !reflect.DeepEqual(cfg.trend(), current.trend()) {
+		settings = append(settings, "trend")
+	}
+	return settings
+}